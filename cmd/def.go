@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/output"
+	"github.com/grant-wade/codeclip/internal/symbols"
+	"github.com/spf13/cobra"
+)
+
+// Whether to clip every definition of an ambiguous symbol
+var defAll bool
+
+var defCmd = &cobra.Command{
+	Use:   "def <symbol>",
+	Short: "Clip the complete definition of a function, method or type",
+	Long: `Find where a symbol is declared and clip its complete definition, including
+its doc comment. The symbol can be a plain Name, a Type.Method, a pkg.Func or a
+pkg.Type.Method; a package may also be given by directory or file name.
+
+When several declarations match, they are listed with their path and line so the
+symbol can be qualified; pass --all to clip every one of them instead.
+
+Examples:
+  codeclip def ParseHeaders
+  codeclip def Source.ReadFile
+  codeclip def finder.Pipeline
+  codeclip def Greeter.greet --lang python
+  codeclip def New --all`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		symbol, err := symbols.ParseSymbol(args[0])
+		if err != nil {
+			return err
+		}
+
+		opts, err := finderOptions()
+		if err != nil {
+			return err
+		}
+
+		src, err := openSource()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		files, walkErr := finder.StreamFiles(ctx, src, nil, opts)
+		defs, err := symbols.FindDefinitions(ctx, src, files, symbol, symbols.Options{
+			Limits:   opts.Limits,
+			Warnings: opts.Warnings,
+		})
+		if err != nil {
+			return err
+		}
+		if err := <-walkErr; err != nil {
+			return err
+		}
+
+		if len(defs) == 0 {
+			return fmt.Errorf("no definition of %s found", symbol)
+		}
+		if len(defs) > 1 && !defAll {
+			fmt.Fprintf(os.Stderr, "%d definitions of %s:\n", len(defs), symbol)
+			for _, def := range defs {
+				fmt.Fprintf(os.Stderr, "  %s:%d  %s %s\n", finder.Label(def.Path, def.Rev), def.Line, def.Type, def.QualifiedName())
+			}
+			return fmt.Errorf("%s is ambiguous: qualify it as Type.Method or pkg.Func, or pass --all", symbol)
+		}
+
+		formatted := output.FormatDefinitions(defs)
+		stats := output.CalculateStats(formatted)
+
+		if maxTokens > 0 && stats.EstimatedTokens > maxTokens {
+			return fmt.Errorf("output exceeds token limit: %d > %d", stats.EstimatedTokens, maxTokens)
+		}
+
+		err = output.CopyToTarget(formatted, outputTarget)
+		if err != nil {
+			return err
+		}
+
+		warnings := opts.Warnings.List()
+		output.PrintSummary(stats, defs, warnings)
+		return checkStrict(warnings)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(defCmd)
+
+	defCmd.Flags().BoolVar(&defAll, "all", false, "Clip every matching definition instead of listing them")
+}
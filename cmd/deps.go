@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/grant-wade/codeclip/internal/deps"
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// How many imports away from the given files to follow
+var depsDepth int
+
+var depsCmd = &cobra.Command{
+	Use:   "deps <file...>",
+	Short: "Clip files together with the local files they import",
+	Long: `Clip the given files and the files of the tree they import, following those
+imports in turn up to --depth levels (0 for the whole closure).
+
+Imports are resolved to files under --path: Go packages of the module declared in
+go.mod, Python relative imports and modules of the tree, JavaScript and TypeScript
+relative import, export and require specifiers, and C and C++ #include "..." lines.
+Standard library and third-party imports are left out. Files are given relative to
+the current directory or to --path.
+
+Examples:
+  codeclip deps cmd/deps.go
+  codeclip deps src/app.ts --depth 1
+  codeclip deps app/main.py --exclude "tests/**"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if depsDepth < 0 {
+			return fmt.Errorf("--depth must not be negative")
+		}
+
+		opts, err := finderOptions()
+		if err != nil {
+			return err
+		}
+
+		src, err := openSource()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		paths, err := finder.SelectFiles(src, nil, opts)
+		if err != nil {
+			return err
+		}
+		resolver := deps.NewResolver(src, paths)
+
+		roots := make([]string, 0, len(args))
+		for _, arg := range args {
+			root, err := selectedFile(src, paths, arg)
+			if err != nil {
+				return err
+			}
+			roots = append(roots, root)
+		}
+
+		closure, err := resolver.Closure(roots, depsDepth, opts.Warnings)
+		if err != nil {
+			return err
+		}
+
+		result, err := finder.ReadFiles(src, closure, opts)
+		if err != nil {
+			return err
+		}
+
+		formatted := output.FormatFiles(result.Files)
+		stats := output.CalculateStats(formatted)
+
+		if maxTokens > 0 && stats.EstimatedTokens > maxTokens {
+			return fmt.Errorf("output exceeds token limit: %d > %d", stats.EstimatedTokens, maxTokens)
+		}
+
+		err = output.CopyToTarget(formatted, outputTarget)
+		if err != nil {
+			return err
+		}
+
+		warnings := opts.Warnings.List()
+		output.PrintSummary(stats, result, warnings)
+		return checkStrict(warnings)
+	},
+}
+
+// selectedFile finds the display path of a file given on the command line,
+// either as a path or relative to --path
+func selectedFile(src finder.Source, paths []string, arg string) (string, error) {
+	for _, candidate := range []string{arg, src.Path(arg)} {
+		name, ok := src.Name(candidate)
+		if !ok {
+			continue
+		}
+		for _, selected := range paths {
+			if other, _ := src.Name(selected); other == name {
+				return selected, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s is not a selected file under %s", arg, inputPath)
+}
+
+func init() {
+	rootCmd.AddCommand(depsCmd)
+
+	depsCmd.Flags().IntVar(&depsDepth, "depth", 0, "Follow imports this many levels deep (0 for no limit)")
+}
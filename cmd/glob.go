@@ -1,45 +1,79 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/grant-wade/codeclip/internal/finder"
 	"github.com/grant-wade/codeclip/internal/output"
 	"github.com/spf13/cobra"
 )
 
 var globCmd = &cobra.Command{
-	Use:   "glob [pattern]",
+	Use:   "glob [pattern...]",
 	Short: "Select files using glob pattern and copy to clipboard",
-	Long: `Select files matching the provided glob pattern and copy their contents to clipboard.
+	Long: `Select files matching the provided glob patterns and copy their contents to clipboard.
+Patterns can be combined with the --include, --exclude and --lang selectors.
 Examples:
   codeclip glob "**/*.go"
-  codeclip glob "src/**/*.{js,ts}" --output file.txt`,
-	Args: cobra.ExactArgs(1),
+  codeclip glob "src/**/*.{js,ts}" --output file.txt
+  codeclip glob --lang go,sql --exclude "*_test.go" --exclude "migrations/old/**"
+  codeclip glob --changed --untracked
+  codeclip glob "internal/finder/*.go" --exclude "*_test.go" --with-tests
+  codeclip glob "**/*.py" --path release.tar.gz`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		pattern := args[0]
-		
-		files, err := finder.FindFilesByGlob(inputPath, pattern)
+		if len(args) == 0 && !hasSelectors() {
+			return fmt.Errorf("no files selected: provide a pattern or a selection flag")
+		}
+
+		opts, err := finderOptions()
 		if err != nil {
 			return err
 		}
-		
-		result, err := finder.ReadFiles(files)
+
+		src, err := openSource()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		// Read files as the walk finds them
+		files, walkErr := finder.StreamFiles(ctx, src, args, opts)
+		result, err := finder.ReadStream(ctx, src, files, opts)
 		if err != nil {
 			return err
 		}
-		
-		formatted := output.FormatFiles(result)
+		if err := <-walkErr; err != nil {
+			return err
+		}
+
+		if withTests {
+			result, err = addPairedTests(src, opts, result)
+			if err != nil {
+				return err
+			}
+		}
+
+		formatted := output.FormatFiles(result.Files)
 		stats := output.CalculateStats(formatted)
-		
+
 		err = output.CopyToTarget(formatted, outputTarget)
 		if err != nil {
 			return err
 		}
-		
-		output.PrintSummary(stats, result)
-		return nil
+
+		warnings := opts.Warnings.List()
+		output.PrintSummary(stats, result, warnings)
+		return checkStrict(warnings)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(globCmd)
-}
\ No newline at end of file
+
+	globCmd.Flags().BoolVar(&withTests, "with-tests", false, "Also copy the test files that go with the selected files")
+}
@@ -14,7 +14,7 @@ import (
 var includeDocstrings bool
 
 var headersCmd = &cobra.Command{
-	Use:   "headers [file or glob pattern]",
+	Use:   "headers [file or glob pattern...]",
 	Short: "Extract headers (functions, classes, etc.) from code files",
 	Long: `Extract and display structural elements (headers) from code files.
 This command identifies functions, classes, methods, and other important structures
@@ -24,41 +24,80 @@ Examples:
   codeclip headers main.go
   codeclip headers "**/*.go"
   codeclip headers --output headers.md "src/**/*.{js,ts}"
-  codeclip headers --docstrings "**/*.py"`,
-	Args: cobra.ExactArgs(1),
+  codeclip headers --docstrings "**/*.py"
+  codeclip headers --lang python --exclude "tests/**"
+  codeclip headers --staged`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		pattern := args[0]
+		if len(args) == 0 && !hasSelectors() {
+			return fmt.Errorf("no files selected: provide a file, pattern or a selection flag")
+		}
+
+		opts, err := finderOptions()
+		if err != nil {
+			return err
+		}
 
-		// Check if the input is a single file or a glob pattern
+		src, err := openSource()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		// Single files are added directly, everything else goes through the
+		// selectors. With --rev every file must come from the revision.
 		files := []string{}
-		if isSingleFile(pattern) {
-			// Add single file directly
-			files = append(files, pattern)
-		} else {
-			// Find files matching the glob pattern
-			globFiles, err := finder.FindFilesByGlob(inputPath, pattern)
+		var patterns []string
+		for _, arg := range args {
+			if src.Rev == "" && isSingleFile(arg) {
+				files = append(files, arg)
+			} else {
+				patterns = append(patterns, arg)
+			}
+		}
+
+		var sourceFiles []string
+		if len(patterns) > 0 || len(args) == 0 {
+			// Find files matching the glob patterns
+			sourceFiles, err = finder.SelectFiles(src, patterns, opts)
 			if err != nil {
 				return fmt.Errorf("failed to find files: %w", err)
 			}
-			files = globFiles
 		}
+		singleFiles := len(files)
+		files = append(files, sourceFiles...)
 
 		if len(files) == 0 {
-			return fmt.Errorf("no files found matching pattern: %s", pattern)
+			return fmt.Errorf("no files found matching: %s", strings.Join(args, " "))
 		}
 
 		var allHeaders strings.Builder
 		allHeaders.WriteString("# Code Structure Headers\n\n")
 
-		// Process each file
-		for _, filePath := range files {
-			headers, err := finder.CollectHeaders(filePath)
+		// Process each file; single files come from disk, the rest from the source
+		for i, filePath := range files {
+			var headers []finder.HeaderElement
+			if i < singleFiles {
+				headers, err = finder.CollectHeaders(filePath)
+			} else {
+				var data []byte
+				data, err = src.ReadFile(filePath)
+				if err == nil {
+					headers, err = finder.ParseHeaders(filePath, data)
+				}
+			}
 			if err != nil {
-				return fmt.Errorf("failed to collect headers from %s: %w", filePath, err)
+				if err := opts.Warnings.Record(filePath, "headers", err); err != nil {
+					return fmt.Errorf("failed to collect headers from %s: %w", filePath, err)
+				}
+				continue
 			}
 
 			if len(headers) > 0 {
-				allHeaders.WriteString(fmt.Sprintf("## %s\n\n", filePath))
+				allHeaders.WriteString(fmt.Sprintf("## %s\n\n", finder.Label(filePath, src.Rev)))
+				if aliases := opts.Aliases.For(filePath); len(aliases) > 0 {
+					allHeaders.WriteString(fmt.Sprintf("Also at: %s\n\n", strings.Join(aliases, ", ")))
+				}
 				allHeaders.WriteString("```\n")
 				allHeaders.WriteString(finder.FormatHeaders(headers, includeDocstrings))
 				allHeaders.WriteString("```\n\n")
@@ -69,14 +108,15 @@ Examples:
 		stats := output.CalculateStats(formatted)
 
 		// Copy to target (clipboard, stdout, or file)
-		err := output.CopyToTarget(formatted, outputTarget)
+		err = output.CopyToTarget(formatted, outputTarget)
 		if err != nil {
 			return fmt.Errorf("failed to copy output: %w", err)
 		}
 
 		// Print summary
-		output.PrintSummary(stats, files)
-		return nil
+		warnings := opts.Warnings.List()
+		output.PrintSummary(stats, files, warnings)
+		return checkStrict(warnings)
 	},
 }
 
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/output"
+	"github.com/grant-wade/codeclip/internal/symbols"
+	"github.com/spf13/cobra"
+)
+
+var refsCmd = &cobra.Command{
+	Use:   "refs <symbol>",
+	Short: "Clip every usage of a symbol with the function it is used in",
+	Long: `Find the usages of a symbol across the tree and clip each one together with
+its enclosing function or type, so the callers of a function can be reviewed before
+its signature changes. Usages outside any function get --context lines instead.
+
+Only whole identifiers in code count: mentions in comments and string literals, and
+the symbol's own declarations, are left out. A qualified Type.Method or pkg.Func
+matches member accesses and qualified calls such as x.Method or pkg.Func, and the
+bare name within its own package or type.
+
+Examples:
+  codeclip refs ParseHeaders
+  codeclip refs Source.ReadFile
+  codeclip refs finder.Pipeline --lang go`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		symbol, err := symbols.ParseSymbol(args[0])
+		if err != nil {
+			return err
+		}
+
+		opts, err := finderOptions()
+		if err != nil {
+			return err
+		}
+
+		src, err := openSource()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		files, walkErr := finder.StreamFiles(ctx, src, nil, opts)
+		refs, err := symbols.FindReferences(ctx, src, files, symbol, symbols.Options{
+			ContextLines: contextLines,
+			Limits:       opts.Limits,
+			Warnings:     opts.Warnings,
+		})
+		if err != nil {
+			return err
+		}
+		if err := <-walkErr; err != nil {
+			return err
+		}
+
+		if len(refs) == 0 {
+			return fmt.Errorf("no references to %s found", symbol)
+		}
+
+		formatted := output.FormatReferences(refs)
+		stats := output.CalculateStats(formatted)
+
+		if maxTokens > 0 && stats.EstimatedTokens > maxTokens {
+			return fmt.Errorf("output exceeds token limit: %d > %d", stats.EstimatedTokens, maxTokens)
+		}
+
+		err = output.CopyToTarget(formatted, outputTarget)
+		if err != nil {
+			return err
+		}
+
+		warnings := opts.Warnings.List()
+		output.PrintSummary(stats, refs, warnings)
+		return checkStrict(warnings)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(refsCmd)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/grant-wade/codeclip/internal/git"
+	"github.com/grant-wade/codeclip/internal/output"
+	"github.com/grant-wade/codeclip/internal/review"
+	"github.com/spf13/cobra"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review <base>..<head>",
+	Short: "Clip a diff together with the full functions and types it touches",
+	Long: `Build a review bundle for a range of commits: the unified diff of each changed
+file, followed by the complete current text of every function or type the diff touches.
+
+A range with three dots (base...head) compares head against the merge base. A single
+ref compares that ref against the working tree.
+
+Examples:
+  codeclip review main..feature
+  codeclip review main...HEAD --lang go
+  codeclip review HEAD~3 --output review.md`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := git.Open(inputPath)
+		if err != nil {
+			return err
+		}
+
+		opts, err := finderOptions()
+		if err != nil {
+			return err
+		}
+
+		bundle, err := review.Build(repo, args[0], review.Options{
+			ContextLines: contextLines,
+			Selection:    opts,
+			Warnings:     opts.Warnings,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to build review: %w", err)
+		}
+
+		if len(bundle.Files) == 0 {
+			return fmt.Errorf("no changes found in %s", args[0])
+		}
+
+		formatted := output.FormatReview(bundle)
+		stats := output.CalculateStats(formatted)
+
+		if maxTokens > 0 && stats.EstimatedTokens > maxTokens {
+			return fmt.Errorf("output exceeds token limit: %d > %d", stats.EstimatedTokens, maxTokens)
+		}
+
+		err = output.CopyToTarget(formatted, outputTarget)
+		if err != nil {
+			return err
+		}
+
+		warnings := opts.Warnings.List()
+		output.PrintSummary(stats, bundle, warnings)
+		return checkStrict(warnings)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+}
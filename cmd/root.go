@@ -1,6 +1,16 @@
 package cmd
 
 import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/git"
 	"github.com/spf13/cobra"
 )
 
@@ -12,13 +22,229 @@ var (
 	estimateTokens bool
 	maxTokens      int
 	inputPath      string
+	noIgnore       bool
+	includes       []string
+	excludes       []string
+	languages      []string
+	gitChanged     bool
+	gitStaged      bool
+	gitUntracked   bool
+	gitSince       string
+	maxFileSize    string
+	allowSkipped   []string
+	jobs           int
+	maxMemory      string
+	revision       string
+	strict         bool
+	followSymlinks bool
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "codeclip",
 	Short: "Copy code to clipboard with advanced filtering",
 	Long: `A CLI tool for quickly copying codebases to your clipboard with support
-for advanced filtering, search, and formatting options.`,
+for advanced filtering, search, and formatting options.
+
+Language detection can be extended with a JSON file at
+<user config dir>/codeclip/languages.json, for example:
+  {"filenames": {"Tiltfile": "python"}, "extensions": {".tpl": "html"}, "interpreters": {"bun": "typescript"}}`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadLanguageConfig()
+	},
+}
+
+// loadLanguageConfig merges the user's language detection rules, if any
+func loadLanguageConfig() error {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	return finder.LoadLanguageConfig(filepath.Join(configDir, "codeclip", "languages.json"))
+}
+
+// openSource opens the --path directory or archive, or with --rev the tree of
+// that revision beneath --path. The caller must close it.
+func openSource() (finder.Source, error) {
+	if revision == "" {
+		return finder.OpenSource(inputPath)
+	}
+
+	repo, err := git.Open(inputPath)
+	if err != nil {
+		return finder.Source{}, err
+	}
+	commit, err := repo.ResolveCommit(revision)
+	if err != nil {
+		return finder.Source{}, err
+	}
+	entries, err := repo.ListTree(commit)
+	if err != nil {
+		return finder.Source{}, err
+	}
+
+	// Blobs are only read from git when a file is actually opened
+	fsys := finder.NewMemFS()
+	for _, entry := range entries {
+		mode := fs.FileMode(0o644)
+		if entry.Executable {
+			mode = 0o755
+		}
+		object := entry.Object
+		fsys.AddLazyFile(entry.Path, entry.Size, mode, time.Time{}, func() ([]byte, error) {
+			return repo.ReadBlob(object)
+		})
+	}
+
+	return finder.Source{FS: fsys, Root: inputPath, Rev: revision}, nil
+}
+
+// finderOptions builds the file selection options from the global flags
+func finderOptions() (finder.Options, error) {
+	opts := finder.Options{
+		NoIgnore:  noIgnore,
+		Include:   includes,
+		Exclude:   excludes,
+		Languages: languages,
+		Warnings:  &finder.Warnings{},
+
+		FollowSymlinks: followSymlinks,
+		Aliases:        &finder.Aliases{},
+	}
+
+	size, err := parseSize(maxFileSize)
+	if err != nil {
+		return opts, err
+	}
+	opts.MaxFileSize = size
+
+	memory, err := parseSize(maxMemory)
+	if err != nil {
+		return opts, err
+	}
+	opts.Limits = finder.Limits{Jobs: jobs, MaxMemory: memory}
+
+	for _, allow := range allowSkipped {
+		reason, err := parseSkipReason(allow)
+		if err != nil {
+			return opts, err
+		}
+		opts.Allow = append(opts.Allow, reason)
+	}
+
+	only, err := gitSelection()
+	if err != nil {
+		return opts, err
+	}
+	opts.Only = only
+
+	return opts, nil
+}
+
+// checkStrict fails the command when --strict is set and any file had to be
+// left out. The output has already been produced by then.
+func checkStrict(warnings []finder.Warning) error {
+	if strict && len(warnings) > 0 {
+		return fmt.Errorf("%d file(s) could not be read", len(warnings))
+	}
+	return nil
+}
+
+// parseSize converts a size such as "512KB" or "2MB" to bytes. A bare number
+// is taken as bytes and zero disables the limit.
+func parseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.size
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size: %s", value)
+	}
+
+	return int64(number * float64(multiplier)), nil
+}
+
+// parseSkipReason validates a category passed to --allow
+func parseSkipReason(value string) (finder.SkipReason, error) {
+	for _, reason := range finder.SkipReasons {
+		if strings.EqualFold(value, string(reason)) {
+			return reason, nil
+		}
+	}
+	return "", fmt.Errorf("unknown --allow category %q (expected binary, generated, lockfile or oversized)", value)
+}
+
+// hasSelectors reports whether any selection flag was given, so commands
+// can run without a positional pattern
+func hasSelectors() bool {
+	return len(includes) > 0 || len(languages) > 0 ||
+		gitChanged || gitStaged || gitUntracked || gitSince != ""
+}
+
+// gitSelection returns the files picked by the git selection flags, or nil
+// when none of them are set
+func gitSelection() ([]string, error) {
+	if !gitChanged && !gitStaged && !gitUntracked && gitSince == "" {
+		return nil, nil
+	}
+
+	repo, err := git.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var lists [][]string
+	if gitChanged {
+		files, err := repo.ChangedFiles()
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, files)
+	}
+	if gitStaged {
+		files, err := repo.StagedFiles()
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, files)
+	}
+	if gitUntracked {
+		files, err := repo.UntrackedFiles()
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, files)
+	}
+	if gitSince != "" {
+		files, err := repo.FilesSince(gitSince)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, files)
+	}
+
+	return git.MergePaths(lists...), nil
 }
 
 // Execute runs the root command
@@ -31,7 +257,22 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&entireFunction, "function", "f", false, "Include entire function/method containing matches")
 	rootCmd.PersistentFlags().BoolVarP(&fuzzySearch, "fuzzy", "z", false, "Enable fuzzy matching for search terms")
 	rootCmd.PersistentFlags().StringVarP(&outputTarget, "output", "o", "clipboard", "Output destination (clipboard, stdout, or file path)")
-	rootCmd.PersistentFlags().BoolVarP(&estimateTokens, "estimate", "e", true, "Estimate token count in output")
+	rootCmd.PersistentFlags().BoolVar(&estimateTokens, "estimate", true, "Estimate token count in output")
 	rootCmd.PersistentFlags().IntVarP(&maxTokens, "max-tokens", "m", 0, "Maximum tokens to copy (0 for unlimited)")
-	rootCmd.PersistentFlags().StringVarP(&inputPath, "path", "p", ".", "Path to search in")
+	rootCmd.PersistentFlags().StringVarP(&inputPath, "path", "p", ".", "Path to search in: a directory, or a .zip, .tar or .tar.gz archive")
+	rootCmd.PersistentFlags().StringVar(&revision, "rev", "", "Read files from this git revision instead of the working tree")
+	rootCmd.PersistentFlags().BoolVar(&followSymlinks, "follow-symlinks", false, "Follow symlinked files and directories, listing each file once")
+	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Exit with an error if any file could not be read")
+	rootCmd.PersistentFlags().BoolVar(&noIgnore, "no-ignore", false, "Don't respect .gitignore, .codeclipignore or .git/info/exclude rules")
+	rootCmd.PersistentFlags().StringArrayVar(&includes, "include", nil, "Only select files matching this glob (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&excludes, "exclude", nil, "Skip files and directories matching this glob (repeatable)")
+	rootCmd.PersistentFlags().StringSliceVar(&languages, "lang", nil, "Only select files in these languages (e.g. go,python)")
+	rootCmd.PersistentFlags().BoolVar(&gitChanged, "changed", false, "Only select files changed relative to HEAD (staged or not)")
+	rootCmd.PersistentFlags().BoolVar(&gitStaged, "staged", false, "Only select files with staged changes")
+	rootCmd.PersistentFlags().BoolVar(&gitUntracked, "untracked", false, "Only select untracked files that aren't ignored")
+	rootCmd.PersistentFlags().StringVar(&gitSince, "since", "", "Only select files changed since the branch diverged from this ref")
+	rootCmd.PersistentFlags().StringVar(&maxFileSize, "max-file-size", "1MB", "Skip files larger than this size (e.g. 512KB, 2MB; 0 for no limit)")
+	rootCmd.PersistentFlags().StringSliceVar(&allowSkipped, "allow", nil, "Read files normally skipped: binary, generated, lockfile, oversized")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", 0, "Number of files to read and search in parallel (0 for one per CPU)")
+	rootCmd.PersistentFlags().StringVar(&maxMemory, "max-memory", "256MB", "Limit on file content held in memory while reading (0 for no limit)")
 }
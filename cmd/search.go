@@ -1,38 +1,163 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/grant-wade/codeclip/internal/finder"
 	"github.com/grant-wade/codeclip/internal/output"
 	"github.com/grant-wade/codeclip/internal/search"
+	"github.com/grant-wade/codeclip/internal/symbols"
 	"github.com/spf13/cobra"
 )
 
+// Search pattern flags
+var (
+	fixedStrings bool
+	ignoreCase   bool
+	wholeWord    bool
+	extraPattern []string
+	patternsFile string
+	searchQuery  string
+	topSnippets  int
+	multiline    bool
+	searchIn     []string
+	calleeDepth  int
+	callerDepth  int
+	withTypes    bool
+	typeDepth    int
+	withPreamble bool
+)
+
 var searchCmd = &cobra.Command{
 	Use:   "search [pattern]",
 	Short: "Search for code matching pattern and copy to clipboard",
 	Long: `Search for code matching the provided pattern and copy results to clipboard with context.
+Patterns are regular expressions unless --fixed-strings is given. Several patterns
+can be passed with -e or --patterns-file, and a line matches when any of them does.
+
+With --query, terms are combined with AND, OR, NOT and NEAR/n (within n lines);
+adjacent terms are ANDed and parentheses group. Combined with --function, the
+query must hold within a single function rather than anywhere in the file.
+
+With --function, --callees N and --callers N add the functions the matched ones
+call, or are called by, up to N calls away. Go calls are read from the syntax tree;
+other languages are matched by name, so calls to common names may be left out.
+
+With --with-preamble, each file's snippets are preceded by its package or module
+declaration and imports, so the names they use can be traced.
+
+With --with-tests, the functions of test files that use the matched functions and
+types are added as well.
+
+For Go, --with-types adds the definitions of the types the clipped code uses, from
+its own package or imported packages of the tree, and of the types those use, up
+to --type-depth levels away.
 Examples:
   codeclip search "func GetUser" --function
-  codeclip search "api.call" --context 5`,
-	Args: cobra.ExactArgs(1),
+  codeclip search "api.call" --context 5
+  codeclip search --fuzzy getusr --top 5
+  codeclip search -U 'if err != nil \{\s*return nil'
+  codeclip search "TODO" --since main
+  codeclip search -F "api.call(" --ignore-case
+  codeclip search -w -e Open -e Close
+  codeclip search retry --in strings
+  codeclip search -w ioutil --in comments
+  codeclip search --function --query "ctx.Done() AND select"
+  codeclip search "func HandleOrder" --function --callees 2
+  codeclip search "func validate" --function --callers 1
+  codeclip search "func HandleOrder" --function --with-types
+  codeclip search "func ParseHeaders" --function --with-tests
+  codeclip search "sql.Open" --with-preamble
+  codeclip search --query "db.Exec NOT tx.Rollback"
+  codeclip search --query "Lock() NEAR/5 (Unlock() OR defer)"`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		searchPattern := args[0]
+		searchPatterns, err := searchPatternList(args)
+		if err != nil {
+			return err
+		}
+
+		var kinds []finder.SourceKind
+		for _, value := range searchIn {
+			kind, err := parseSourceKind(value)
+			if err != nil {
+				return err
+			}
+			kinds = append(kinds, kind)
+		}
+		if (calleeDepth > 0 || callerDepth > 0) && !entireFunction {
+			return fmt.Errorf("--callees and --callers need --function")
+		}
+		if topSnippets > 0 && !fuzzySearch {
+			// Only fuzzy matches are scored, so there is nothing to rank by
+			return fmt.Errorf("--top needs --fuzzy")
+		}
+
+		opts, err := finderOptions()
+		if err != nil {
+			return err
+		}
 
-		files, err := finder.FindAllCodeFiles(inputPath)
+		src, err := openSource()
 		if err != nil {
 			return err
 		}
+		defer src.Close()
 
-		searchResults, err := search.SearchInFiles(files, searchPattern, search.Options{
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		// Search files as the walk finds them
+		files, walkErr := finder.StreamFiles(ctx, src, nil, opts)
+		searchResults, err := search.SearchStream(ctx, src, files, searchPatterns, search.Options{
 			ContextLines:   contextLines,
 			EntireFunction: entireFunction,
 			FuzzySearch:    fuzzySearch,
+			FixedStrings:   fixedStrings,
+			IgnoreCase:     ignoreCase,
+			WholeWord:      wholeWord,
+			Query:          searchQuery,
+			Top:            topSnippets,
+			Multiline:      multiline,
+			In:             kinds,
+			Limits:         opts.Limits,
+			Warnings:       opts.Warnings,
+			Aliases:        opts.Aliases,
 		})
 		if err != nil {
 			return err
 		}
+		if err := <-walkErr; err != nil {
+			return err
+		}
+
+		if calleeDepth > 0 || callerDepth > 0 {
+			searchResults, err = expandCalls(ctx, src, opts, searchResults)
+			if err != nil {
+				return err
+			}
+		}
+		if withTypes {
+			searchResults, err = expandTypes(ctx, src, opts, searchResults)
+			if err != nil {
+				return err
+			}
+		}
+		if withTests {
+			searchResults, err = expandTests(ctx, src, opts, searchResults)
+			if err != nil {
+				return err
+			}
+		}
+		if withPreamble {
+			searchResults, err = search.AddPreambles(src, searchResults, opts.Warnings)
+			if err != nil {
+				return err
+			}
+		}
 
 		formatted := output.FormatSearchResults(searchResults)
 		stats := output.CalculateStats(formatted)
@@ -46,11 +171,142 @@ Examples:
 			return err
 		}
 
-		output.PrintSummary(stats, searchResults)
-		return nil
+		warnings := opts.Warnings.List()
+		output.PrintSummary(stats, searchResults, warnings)
+		return checkStrict(warnings)
 	},
 }
 
+// searchPatternList gathers the patterns from the argument, -e flags and
+// --patterns-file, in that order. None may be given with --query.
+func searchPatternList(args []string) ([]string, error) {
+	if searchQuery != "" {
+		if len(args) > 0 || len(extraPattern) > 0 || patternsFile != "" {
+			return nil, fmt.Errorf("--query can't be combined with other search patterns")
+		}
+		return nil, nil
+	}
+
+	patterns := append(args, extraPattern...)
+
+	if patternsFile != "" {
+		data, err := os.ReadFile(patternsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read patterns file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if line != "" {
+				patterns = append(patterns, line)
+			}
+		}
+	}
+
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no search pattern given: provide a pattern, --regexp, --patterns-file or --query")
+	}
+	return patterns, nil
+}
+
+// treeOptions returns the options for walking the tree a second time to
+// expand the results. Calls and types can cross into files the search was
+// narrowed away from, such as unchanged ones with --since, and the search
+// already reported unreadable files and symlink aliases.
+func treeOptions(opts finder.Options) finder.Options {
+	opts.Only = nil
+	opts.Warnings = new(finder.Warnings)
+	opts.Aliases = nil
+	return opts
+}
+
+// expandCalls adds the callees and callers of the matched functions to the
+// results, walking the tree a second time to build its call graph
+func expandCalls(ctx context.Context, src finder.Source, opts finder.Options, results search.SearchResult) (search.SearchResult, error) {
+	opts = treeOptions(opts)
+	files, walkErr := finder.StreamFiles(ctx, src, nil, opts)
+	graph, err := symbols.BuildCallGraph(ctx, src, files, symbols.Options{
+		Limits:   opts.Limits,
+		Warnings: opts.Warnings,
+	})
+	if err != nil {
+		return results, err
+	}
+	if err := <-walkErr; err != nil {
+		return results, err
+	}
+	return graph.Expand(results, calleeDepth, callerDepth), nil
+}
+
+// expandTypes adds the definitions of the Go types the results use, walking
+// the tree a second time to find them
+func expandTypes(ctx context.Context, src finder.Source, opts finder.Options, results search.SearchResult) (search.SearchResult, error) {
+	opts = treeOptions(opts)
+	files, walkErr := finder.StreamFiles(ctx, src, nil, opts)
+	index, err := symbols.BuildTypeIndex(ctx, src, files, symbols.Options{
+		Limits:   opts.Limits,
+		Warnings: opts.Warnings,
+	})
+	if err != nil {
+		return results, err
+	}
+	if err := <-walkErr; err != nil {
+		return results, err
+	}
+	return index.Expand(results, typeDepth), nil
+}
+
+// expandTests adds the test functions that use the functions and types the
+// results cover, walking the tree a second time to find them
+func expandTests(ctx context.Context, src finder.Source, opts finder.Options, results search.SearchResult) (search.SearchResult, error) {
+	names, err := symbols.CoveredNames(src, results, opts.Warnings)
+	if err != nil {
+		return results, err
+	}
+
+	opts = treeOptions(opts)
+	files, walkErr := finder.StreamFiles(ctx, src, nil, opts)
+	tests, err := symbols.FindTests(ctx, src, files, names, symbols.Options{
+		Limits:   opts.Limits,
+		Warnings: opts.Warnings,
+	})
+	if err != nil {
+		return results, err
+	}
+	if err := <-walkErr; err != nil {
+		return results, err
+	}
+	return symbols.AddTests(results, tests), nil
+}
+
+// parseSourceKind validates a kind of source text passed to --in
+func parseSourceKind(value string) (finder.SourceKind, error) {
+	switch strings.ToLower(value) {
+	case "code":
+		return finder.KindCode, nil
+	case "comments", "comment":
+		return finder.KindComment, nil
+	case "strings", "string":
+		return finder.KindString, nil
+	}
+	return 0, fmt.Errorf("unknown --in kind %q (expected code, comments or strings)", value)
+}
+
 func init() {
 	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().BoolVarP(&fixedStrings, "fixed-strings", "F", false, "Treat patterns as literal text instead of regular expressions")
+	searchCmd.Flags().BoolVarP(&ignoreCase, "ignore-case", "i", false, "Match without regard to case")
+	searchCmd.Flags().BoolVarP(&wholeWord, "word", "w", false, "Only match whole words")
+	searchCmd.Flags().StringArrayVarP(&extraPattern, "regexp", "e", nil, "Search for this pattern as well (repeatable)")
+	searchCmd.Flags().StringVar(&patternsFile, "patterns-file", "", "Read additional patterns from a file, one per line")
+	searchCmd.Flags().BoolVarP(&multiline, "multiline", "U", false, "Match patterns against whole files so they can span lines")
+	searchCmd.Flags().IntVar(&topSnippets, "top", 0, "With --fuzzy, only copy the N best scoring snippets (0 for all)")
+	searchCmd.Flags().StringSliceVar(&searchIn, "in", nil, "Only match within code, comments or strings (repeatable)")
+	searchCmd.Flags().IntVar(&calleeDepth, "callees", 0, "With --function, also clip the functions the matches call, up to N calls deep")
+	searchCmd.Flags().IntVar(&callerDepth, "callers", 0, "With --function, also clip the functions calling the matches, up to N calls deep")
+	searchCmd.Flags().BoolVar(&withPreamble, "with-preamble", false, "Precede each file's snippets with its package declaration and imports")
+	searchCmd.Flags().BoolVar(&withTests, "with-tests", false, "Also clip the test functions that use the matched functions and types")
+	searchCmd.Flags().BoolVar(&withTypes, "with-types", false, "For Go, also clip the definitions of the types the matches use")
+	searchCmd.Flags().IntVar(&typeDepth, "type-depth", 2, "With --with-types, follow the types used by added types up to N levels from the matches (0 for no limit)")
+	searchCmd.Flags().StringVarP(&searchQuery, "query", "q", "", "Boolean query of terms joined by AND, OR, NOT and NEAR/n")
 }
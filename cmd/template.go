@@ -34,8 +34,19 @@ If a file isn't found, the tag will be replaced with an empty string.`,
 			return fmt.Errorf("template file not found: %s", templatePath)
 		}
 
+		opts, err := finderOptions()
+		if err != nil {
+			return err
+		}
+
+		src, err := openSource()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
 		// Process template
-		parser := template.NewParser(inputPath)
+		parser := template.NewParser(src, opts)
 		processed, err := parser.Process(templatePath)
 		if err != nil {
 			return fmt.Errorf("template processing failed: %w", err)
@@ -56,9 +67,10 @@ If a file isn't found, the tag will be replaced with an empty string.`,
 		}
 
 		// Print summary
-		output.PrintSummary(stats, []string{templatePath})
+		warnings := opts.Warnings.List()
+		output.PrintSummary(stats, []string{templatePath}, warnings)
 
-		return nil
+		return checkStrict(warnings)
 	},
 }
 
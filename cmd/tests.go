@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/output"
+	"github.com/grant-wade/codeclip/internal/symbols"
+	"github.com/spf13/cobra"
+)
+
+// Whether to add the tests that go with the selected code
+var withTests bool
+
+var testsCmd = &cobra.Command{
+	Use:   "tests <file|symbol>",
+	Short: "Clip the tests that go with a file or symbol",
+	Long: `Clip the tests for a file or a symbol.
+
+For a file, its test files are found by the naming conventions of its language:
+foo_test.go for foo.go, foo.test.ts, foo.spec.ts or __tests__/foo.test.ts for
+foo.ts, test_x.py or tests/test_x.py for x.py, FooTest.java for Foo.java, and so
+on. Test files pair when they sit next to the file or in a test directory of its
+own directory or a parent.
+
+For a symbol, every function of a test file that uses its name in code is clipped.
+
+Examples:
+  codeclip tests internal/finder/finder.go
+  codeclip tests app/x.py
+  codeclip tests ParseHeaders
+  codeclip tests Source.ReadFile --lang go`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := finderOptions()
+		if err != nil {
+			return err
+		}
+
+		src, err := openSource()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		paths, err := finder.SelectFiles(src, nil, opts)
+		if err != nil {
+			return err
+		}
+
+		var formatted string
+		var summary interface{}
+
+		file, fileErr := selectedFile(src, paths, args[0])
+		switch {
+		case fileErr == nil:
+			candidates, err := testCandidates(src, opts)
+			if err != nil {
+				return err
+			}
+			paired := finder.PairedTests(file, candidates)
+			if len(paired) == 0 {
+				return fmt.Errorf("no tests found for %s", args[0])
+			}
+			result, err := finder.ReadFiles(src, paired, opts)
+			if err != nil {
+				return err
+			}
+			formatted, summary = output.FormatFiles(result.Files), result
+
+		case strings.ContainsAny(args[0], `/\`):
+			return fileErr
+
+		default:
+			symbol, err := symbols.ParseSymbol(args[0])
+			if err != nil {
+				return fmt.Errorf("%s is neither a selected file nor a valid symbol", args[0])
+			}
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+
+			tests, err := symbols.FindTests(ctx, src, finder.SendPaths(ctx, paths), []string{symbol.Name}, symbols.Options{
+				Limits:   opts.Limits,
+				Warnings: opts.Warnings,
+			})
+			if err != nil {
+				return err
+			}
+			if len(tests) == 0 {
+				return fmt.Errorf("no tests using %s found", symbol)
+			}
+
+			defs := make([]symbols.Definition, len(tests))
+			for i, test := range tests {
+				defs[i] = test.Definition
+			}
+			formatted, summary = output.FormatDefinitions(defs), defs
+		}
+
+		stats := output.CalculateStats(formatted)
+
+		if maxTokens > 0 && stats.EstimatedTokens > maxTokens {
+			return fmt.Errorf("output exceeds token limit: %d > %d", stats.EstimatedTokens, maxTokens)
+		}
+
+		err = output.CopyToTarget(formatted, outputTarget)
+		if err != nil {
+			return err
+		}
+
+		warnings := opts.Warnings.List()
+		output.PrintSummary(stats, summary, warnings)
+		return checkStrict(warnings)
+	},
+}
+
+// testCandidates lists the files of the tree tests are paired from. The
+// selection filters don't apply, so tests a filter such as
+// --exclude "*_test.go" keeps out of the selection are still found.
+func testCandidates(src finder.Source, opts finder.Options) ([]string, error) {
+	opts = treeOptions(opts)
+	opts.Include, opts.Exclude, opts.Languages = nil, nil, nil
+	return finder.SelectFiles(src, nil, opts)
+}
+
+// addPairedTests reads the test files that go with the files of result and
+// appends them, skipping any already in it
+func addPairedTests(src finder.Source, opts finder.Options, result finder.ReadResult) (finder.ReadResult, error) {
+	paths, err := testCandidates(src, opts)
+	if err != nil {
+		return result, err
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range result.Files {
+		seen[file.Path] = true
+	}
+	var paired []string
+	for _, file := range result.Files {
+		for _, test := range finder.PairedTests(file.Path, paths) {
+			if !seen[test] {
+				seen[test] = true
+				paired = append(paired, test)
+			}
+		}
+	}
+
+	tests, err := finder.ReadFiles(src, paired, opts)
+	if err != nil {
+		return result, err
+	}
+	result.Files = append(result.Files, tests.Files...)
+	result.Skipped = append(result.Skipped, tests.Skipped...)
+	return result, nil
+}
+
+func init() {
+	rootCmd.AddCommand(testsCmd)
+}
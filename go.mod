@@ -3,13 +3,16 @@ module github.com/grant-wade/codeclip
 go 1.23.1
 
 require (
-	github.com/atotto/clipboard v0.1.4 // indirect
-	github.com/bmatcuk/doublestar/v4 v4.8.1 // indirect
-	github.com/fatih/color v1.18.0 // indirect
+	github.com/atotto/clipboard v0.1.4
+	github.com/bmatcuk/doublestar/v4 v4.8.1
+	github.com/fatih/color v1.18.0
+	github.com/spf13/cobra v1.9.1
+)
+
+require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/spf13/cobra v1.9.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 	golang.org/x/sys v0.25.0 // indirect
 )
@@ -0,0 +1,263 @@
+package deps
+
+import (
+	"path"
+	"strings"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// jsExtensions are tried, in order, for a specifier without its extension
+var jsExtensions = []string{".ts", ".tsx", ".d.ts", ".js", ".jsx", ".mjs", ".cjs", ".mts", ".cts"}
+
+// Resolver finds the files of a tree that the local imports of its files
+// refer to
+type Resolver struct {
+	src    finder.Source
+	names  map[string]string   // Display paths of the files imports can resolve to, by name within the source
+	goPkgs map[string][]string // Go files other than tests, by the import path of their package
+}
+
+// NewResolver creates a resolver for the files at paths in src. Imports of
+// anything else, such as standard libraries and third-party packages, are
+// left unresolved.
+func NewResolver(src finder.Source, paths []string) *Resolver {
+	r := &Resolver{
+		src:    src,
+		names:  make(map[string]string),
+		goPkgs: make(map[string][]string),
+	}
+	module, inModule := finder.FindGoModule(src)
+
+	for _, displayPath := range paths {
+		name, ok := src.Name(displayPath)
+		if !ok {
+			continue
+		}
+		r.names[name] = displayPath
+		if inModule && path.Ext(name) == ".go" && !strings.HasSuffix(name, "_test.go") {
+			pkg := module.ImportPath(path.Dir(name))
+			r.goPkgs[pkg] = append(r.goPkgs[pkg], displayPath)
+		}
+	}
+
+	return r
+}
+
+// Resolve returns the display paths of the files that the file at
+// displayPath imports, in the order they are first imported
+func (r *Resolver) Resolve(displayPath string, data []byte) []string {
+	name, ok := r.src.Name(displayPath)
+	if !ok {
+		return nil
+	}
+	dir := path.Dir(name)
+
+	var found []string
+	switch finder.DetectLanguageContent(displayPath, data) {
+	case "go":
+		found = r.goImports(displayPath, data)
+	case "python":
+		found = r.pythonImports(dir, displayPath, data)
+	case "javascript", "typescript":
+		for _, imported := range imports(displayPath, data) {
+			found = append(found, r.jsModule(dir, imported.Name)...)
+		}
+	case "c", "cpp":
+		for _, imported := range imports(displayPath, data) {
+			// Only quoted includes name files of the project
+			if spec, ok := strings.CutPrefix(imported.Name, `"`); ok {
+				found = append(found, r.cHeader(dir, strings.TrimSuffix(spec, `"`))...)
+			}
+		}
+	}
+
+	// A file never depends on itself, and each import is listed once
+	seen := map[string]bool{displayPath: true}
+	var unique []string
+	for _, file := range found {
+		if !seen[file] {
+			seen[file] = true
+			unique = append(unique, file)
+		}
+	}
+	return unique
+}
+
+// goImports resolves the imports of a Go file within the module to every
+// non-test file of the imported package
+func (r *Resolver) goImports(displayPath string, data []byte) []string {
+	var found []string
+	for _, imported := range imports(displayPath, data) {
+		// The regex fallback keeps any alias in front of the quoted path
+		importPath := strings.Trim(imported.Name, `"`)
+		if fields := strings.Fields(imported.Name); len(fields) > 1 {
+			importPath = strings.Trim(fields[len(fields)-1], `"`)
+		}
+		found = append(found, r.goPkgs[importPath]...)
+	}
+	return found
+}
+
+// pythonImports resolves relative imports against the package of the file,
+// and absolute ones against the root and the file's own directory
+func (r *Resolver) pythonImports(dir, displayPath string, data []byte) []string {
+	var found []string
+	for _, imported := range imports(displayPath, data) {
+		module := imported.Parent // "from module import name"
+		names := []string{imported.Name}
+		if module == "" {
+			module, names = imported.Name, nil // "import module"
+		}
+
+		var bases []string
+		if strings.HasPrefix(module, ".") {
+			// Each dot past the first climbs one package up
+			rest := strings.TrimLeft(module, ".")
+			base := dir
+			for i := 1; i < len(module)-len(rest); i++ {
+				base = path.Dir(base)
+			}
+			bases = []string{base}
+			module = rest
+		} else {
+			bases = []string{".", dir}
+		}
+
+		for _, base := range bases {
+			modulePath := path.Join(base, strings.ReplaceAll(module, ".", "/"))
+			if module == "" {
+				found = append(found, r.firstFile(path.Join(base, "__init__.py"))...) // "from . import name"
+			} else if file := r.pythonModule(modulePath); file != nil {
+				found = append(found, r.pythonPackages(base, module)...)
+				found = append(found, file...)
+			}
+
+			// "from package import module" imports a submodule
+			for _, name := range names {
+				if name != "*" {
+					found = append(found, r.pythonModule(path.Join(modulePath, name))...)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// pythonModule returns the file of a module given by its path without
+// extension: a .py file, or the __init__.py of a package
+func (r *Resolver) pythonModule(modulePath string) []string {
+	return r.firstFile(modulePath+".py", path.Join(modulePath, "__init__.py"))
+}
+
+// pythonPackages returns the __init__.py files of the packages a dotted
+// module is in, outermost first, as importing app.models runs app/__init__.py
+// before app/models.py
+func (r *Resolver) pythonPackages(base, module string) []string {
+	var found []string
+	parts := strings.Split(module, ".")
+	for i := 1; i < len(parts); i++ {
+		found = append(found, r.firstFile(path.Join(base, path.Join(parts[:i]...), "__init__.py"))...)
+	}
+	return found
+}
+
+// jsModule resolves a relative specifier the way bundlers and TypeScript do:
+// as written, with an extension added, or as a directory index. Bare package
+// names are left alone.
+func (r *Resolver) jsModule(dir, spec string) []string {
+	if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+		return nil
+	}
+
+	target := path.Join(dir, spec)
+	candidates := []string{target}
+	for _, ext := range jsExtensions {
+		candidates = append(candidates, target+ext)
+	}
+	for _, ext := range jsExtensions {
+		candidates = append(candidates, path.Join(target, "index"+ext))
+	}
+
+	// TypeScript sources are imported by the name of their compiled output
+	if ext := path.Ext(target); ext == ".js" || ext == ".jsx" || ext == ".mjs" || ext == ".cjs" {
+		stem := strings.TrimSuffix(target, ext)
+		candidates = append(candidates, stem+".ts", stem+".tsx", stem+".mts", stem+".cts")
+	}
+
+	return r.firstFile(candidates...)
+}
+
+// cHeader resolves a quoted include the way compilers search for it: next to
+// the including file first, then in the include directories projects usually
+// pass with -I
+func (r *Resolver) cHeader(dir, spec string) []string {
+	return r.firstFile(
+		path.Join(dir, spec),
+		path.Join(dir, "include", spec),
+		path.Join(path.Dir(dir), "include", spec),
+		spec,
+		path.Join("include", spec))
+}
+
+// firstFile returns the display path of the first candidate name that is one
+// of the resolver's files
+func (r *Resolver) firstFile(candidates ...string) []string {
+	for _, name := range candidates {
+		if displayPath, ok := r.names[path.Clean(name)]; ok {
+			return []string{displayPath}
+		}
+	}
+	return nil
+}
+
+// imports returns the Import children of a file's headers
+func imports(displayPath string, data []byte) []finder.HeaderElement {
+	headers, _ := finder.ParseHeaders(displayPath, data)
+
+	var imported []finder.HeaderElement
+	for _, header := range headers {
+		if header.Type == finder.Import {
+			imported = append(imported, header.Children...)
+		}
+	}
+	return imported
+}
+
+// Closure returns roots followed by the files they import and, in turn, the
+// files those import, breadth first up to depth imports away (0 for no
+// limit). Files that can't be read are reported to warnings, or stop the
+// walk when it is nil.
+func (r *Resolver) Closure(roots []string, depth int, warnings *finder.Warnings) ([]string, error) {
+	seen := make(map[string]bool)
+	var order []string
+	for _, root := range roots {
+		if !seen[root] {
+			seen[root] = true
+			order = append(order, root)
+		}
+	}
+
+	frontier := order
+	for level := 0; len(frontier) > 0 && (depth == 0 || level < depth); level++ {
+		var next []string
+		for _, file := range frontier {
+			data, err := r.src.ReadFile(file)
+			if err != nil {
+				if err := warnings.Record(file, "read", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			for _, imported := range r.Resolve(file, data) {
+				if !seen[imported] {
+					seen[imported] = true
+					order = append(order, imported)
+					next = append(next, imported)
+				}
+			}
+		}
+		frontier = next
+	}
+	return order, nil
+}
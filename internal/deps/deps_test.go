@@ -0,0 +1,134 @@
+package deps
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// depsTree has local imports in Go, Python, TypeScript and C, next to
+// imports of standard and third-party packages that stay unresolved
+var depsTree = fstest.MapFS{
+	"go.mod": {Data: []byte("module example.com/app\n")},
+	"main.go": {Data: []byte(`package main
+
+import (
+	"fmt"
+
+	st "example.com/app/store"
+)
+
+func main() { fmt.Println(st.Get()) }
+`)},
+	"store/store.go":      {Data: []byte("package store\n\nfunc Get() int { return cached }\n")},
+	"store/cache.go":      {Data: []byte("package store\n\nvar cached = 1\n")},
+	"store/store_test.go": {Data: []byte("package store\n")},
+
+	"run.py":              {Data: []byte("import os\nimport app.models\n")},
+	"app/__init__.py":     {Data: []byte("")},
+	"app/models.py":       {Data: []byte("from . import helpers\n")},
+	"app/helpers.py":      {Data: []byte("from .models import Order\n")},
+	"app/views.py":        {Data: []byte("from .models import Order\nfrom .. import run\n")},
+	"app/api/__init__.py": {Data: []byte("")},
+	"app/api/util.py":     {Data: []byte("")},
+	"app/api/handlers.py": {Data: []byte("from app.api import util\n")},
+
+	"web/index.ts":     {Data: []byte("import { a } from \"./util.js\";\nimport lib from \"./lib\";\nimport React from \"react\";\n")},
+	"web/util.ts":      {Data: []byte("export const a = 1;\n")},
+	"web/lib/index.ts": {Data: []byte("export default 1;\n")},
+
+	"src/main.c":       {Data: []byte("#include <stdio.h>\n#include \"parse.h\"\n")},
+	"include/parse.h":  {Data: []byte("int parse(void);\n")},
+	"src/local.h":      {Data: []byte("")},
+	"src/uses_local.c": {Data: []byte("#include \"local.h\"\n")},
+}
+
+// depsPaths lists every file of depsTree
+func depsPaths() []string {
+	var paths []string
+	for name := range depsTree {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"main.go", []string{"store/cache.go", "store/store.go"}},
+		{"run.py", []string{"app/__init__.py", "app/models.py"}},
+		{"app/models.py", []string{"app/__init__.py", "app/helpers.py"}},
+		{"app/views.py", []string{"app/models.py", "run.py"}},
+		{"app/api/handlers.py", []string{"app/__init__.py", "app/api/__init__.py", "app/api/util.py"}},
+		{"web/index.ts", []string{"web/util.ts", "web/lib/index.ts"}},
+		{"src/main.c", []string{"include/parse.h"}},
+		{"src/uses_local.c", []string{"src/local.h"}},
+		{"store/cache.go", nil},
+	}
+
+	src := finder.FSSource(depsTree, "")
+	resolver := NewResolver(src, depsPaths())
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := resolver.Resolve(tt.file, depsTree[tt.file].Data)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%s) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosure(t *testing.T) {
+	src := finder.FSSource(depsTree, "")
+	resolver := NewResolver(src, depsPaths())
+
+	tests := []struct {
+		name  string
+		roots []string
+		depth int
+		want  []string
+	}{
+		{"whole closure with a cycle", []string{"run.py"}, 0, []string{"run.py", "app/__init__.py", "app/models.py", "app/helpers.py"}},
+		{"one level", []string{"run.py"}, 1, []string{"run.py", "app/__init__.py", "app/models.py"}},
+		{"roots listed once", []string{"main.go", "store/store.go", "main.go"}, 0, []string{"main.go", "store/store.go", "store/cache.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Closure(tt.roots, tt.depth, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Closure() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosureUnreadable(t *testing.T) {
+	src := finder.FSSource(depsTree, "")
+	resolver := NewResolver(src, depsPaths())
+
+	if _, err := resolver.Closure([]string{"missing.py"}, 0, nil); err == nil {
+		t.Error("Closure() without warnings succeeded on a missing file")
+	}
+
+	warnings := &finder.Warnings{}
+	got, err := resolver.Closure([]string{"missing.py", "src/main.c"}, 0, warnings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"missing.py", "src/main.c", "include/parse.h"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Closure() = %q, want %q", got, want)
+	}
+	if list := warnings.List(); len(list) != 1 || list[0].Path != "missing.py" {
+		t.Errorf("Closure() warnings = %v, want one for missing.py", list)
+	}
+}
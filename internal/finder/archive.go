@@ -0,0 +1,83 @@
+package finder
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// IsArchive reports whether a path names a supported archive format
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// openArchive opens a zip or tar archive as a file system
+func openArchive(path string) (fs.FS, io.Closer, error) {
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		reader, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open zip archive: %w", err)
+		}
+		return reader, reader, nil
+	}
+
+	fsys, err := readTar(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open tar archive: %w", err)
+	}
+	return fsys, nil, nil
+}
+
+// readTar loads the regular files of a tar archive, optionally gzipped, into memory
+func readTar(path string) (*MemFS, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	fsys := NewMemFS()
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, err
+		}
+		fsys.AddFile(header.Name, data, fs.FileMode(header.Mode), header.ModTime)
+	}
+
+	return fsys, nil
+}
@@ -0,0 +1,234 @@
+package finder
+
+import (
+	"regexp"
+	"strings"
+)
+
+// rubyBlockOpen matches lines that open a block closed by "end" in Ruby
+var rubyBlockOpen = regexp.MustCompile(`^\s*(def|class|module|if|unless|while|until|case|begin|for)\b|\bdo\s*(\|[^|]*\|)?\s*$`)
+
+// rubyBlockClose matches an "end" that closes a Ruby block: one starting the
+// line, or following a ";" as in "def foo; end"
+var rubyBlockClose = regexp.MustCompile(`(?:^|;)\s*end\b`)
+
+// rubyEndlessDef matches a method defined by a single expression, such as
+// "def area = w * h", which has no "end"
+var rubyEndlessDef = regexp.MustCompile(`^\s*def\s+(?:self\.)?[A-Za-z_]\w*[?!]?(?:\s+|\s*\([^)]*\)\s*)=[^=~>]`)
+
+// maxSignatureLines bounds how far past the declaration an opening brace is looked for
+const maxSignatureLines = 10
+
+// IsBlockType reports whether a header type has a body spanning several lines
+func IsBlockType(t HeaderType) bool {
+	switch t {
+	case Function, Method, Class, Struct, Interface, Enum, Module, Namespace:
+		return true
+	}
+	return false
+}
+
+// FindBlockEnd returns the 1-based line where the block declared at startLine
+// (also 1-based) ends, using indentation for Python, def/end pairs for Ruby
+// and brace matching for everything else. Comments and strings are skipped,
+// so braces or keywords inside them don't count. If no body is found the
+// declaration line itself is returned.
+func FindBlockEnd(language string, lines []string, startLine int) int {
+	return findBlockEnd(language, MaskCode(language, lines), startLine)
+}
+
+// findBlockEnd is FindBlockEnd for lines already passed through MaskCode
+func findBlockEnd(language string, masked []string, startLine int) int {
+	if startLine < 1 || startLine > len(masked) {
+		return startLine
+	}
+
+	switch language {
+	case "python":
+		return findIndentBlockEnd(masked, startLine)
+	case "ruby":
+		return findKeywordBlockEnd(masked, startLine)
+	default:
+		return findBraceBlockEnd(language, masked, startLine)
+	}
+}
+
+// EnclosingBlock returns the index of the innermost block-type header whose
+// span contains line (1-based), such as the method around a statement or the
+// class around a field, or -1 if the line lies outside every block
+func EnclosingBlock(headers []HeaderElement, line int) int {
+	best := -1
+	for i, header := range headers {
+		if !IsBlockType(header.Type) || header.EndLine < header.LineNum ||
+			line < header.LineNum || line > header.EndLine {
+			continue
+		}
+		// Prefer the tightest span; on ties the later declaration is nested
+		if best < 0 || header.EndLine-header.LineNum <= headers[best].EndLine-headers[best].LineNum {
+			best = i
+		}
+	}
+	return best
+}
+
+// findBraceBlockEnd counts braces from the declaration until they balance
+func findBraceBlockEnd(language string, lines []string, startLine int) int {
+	depth := 0
+	opened := false
+
+	for i := startLine - 1; i < len(lines); i++ {
+		line := lines[i]
+
+		// A declaration ending in ";" before any brace has no body
+		if !opened && strings.Contains(line, ";") && !strings.Contains(line, "{") {
+			return i + 1
+		}
+
+		// Neither has one followed by another declaration, such as an
+		// interface member or abstract method written without ";"
+		if !opened && i > startLine-1 && declaresBlock(language, line) {
+			return lastCodeLine(lines, startLine, i)
+		}
+
+		for _, char := range line {
+			switch char {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+
+		if opened && depth <= 0 {
+			return i + 1
+		}
+		if !opened && i-(startLine-1) >= maxSignatureLines {
+			break
+		}
+	}
+
+	if opened {
+		return len(lines)
+	}
+	return startLine
+}
+
+// findIndentBlockEnd finds the last line indented deeper than the declaration.
+// Docstrings and comments are blank once masked, so they never end a block.
+func findIndentBlockEnd(lines []string, startLine int) int {
+	baseIndent := indentWidth(lines[startLine-1])
+
+	// Skip over a signature spanning several lines, which only continues
+	// while brackets are open. Code after the colon is the whole body.
+	bodyStart := startLine
+	depth := 0
+	for i := startLine - 1; i < len(lines) && i-(startLine-1) < maxSignatureLines; i++ {
+		if colon := headerColon(lines[i], &depth); colon != -1 {
+			if strings.TrimSpace(lines[i][colon+1:]) != "" {
+				return i + 1
+			}
+			bodyStart = i + 1
+			break
+		}
+		if depth <= 0 {
+			break
+		}
+	}
+
+	end := bodyStart
+	for i := bodyStart; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		if indentWidth(lines[i]) <= baseIndent {
+			break
+		}
+		end = i + 1
+	}
+
+	return end
+}
+
+// headerColon returns the offset of the first colon outside brackets on a
+// line of a block header, or -1. depth carries the brackets left open by
+// earlier lines of the header.
+func headerColon(line string, depth *int) int {
+	for i, char := range line {
+		switch char {
+		case '(', '[', '{':
+			*depth++
+		case ')', ']', '}':
+			*depth--
+		case ':':
+			if *depth <= 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// findKeywordBlockEnd matches block openers against "end" keywords
+func findKeywordBlockEnd(lines []string, startLine int) int {
+	depth := 0
+
+	for i := startLine - 1; i < len(lines); i++ {
+		line := lines[i]
+
+		if rubyBlockOpen.MatchString(line) && !rubyEndlessDef.MatchString(line) {
+			depth++
+		}
+		depth -= len(rubyBlockClose.FindAllStringIndex(line, -1))
+
+		if depth <= 0 {
+			return i + 1
+		}
+	}
+
+	return len(lines)
+}
+
+// declaresBlock reports whether a line declares a block-type element under
+// the header patterns of its language
+func declaresBlock(language, line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, pattern := range languagePatternRegistry[language] {
+		if !IsBlockType(pattern.ElementType) {
+			continue
+		}
+		matches := pattern.Pattern.FindStringSubmatch(trimmed)
+		if len(matches) > pattern.NameGroup && pattern.NameGroup > 0 &&
+			!isStatement(pattern.ElementType, matches[0], matches[pattern.NameGroup]) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastCodeLine returns the last line before the 0-based index end that isn't
+// blank once masked, and no earlier than startLine
+func lastCodeLine(lines []string, startLine, end int) int {
+	for i := end - 1; i >= startLine; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return i + 1
+		}
+	}
+	return startLine
+}
+
+// indentWidth measures leading whitespace, counting a tab as four spaces
+func indentWidth(line string) int {
+	width := 0
+	for _, char := range line {
+		switch char {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
@@ -0,0 +1,176 @@
+package finder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindBlockEndPython(t *testing.T) {
+	source := strings.Split(`def a(): return 1
+
+def b():
+    return 2
+
+class C(
+    Base,
+):
+    def m(self, x: dict[str, int] = {"k": 1}) -> int:
+        return x
+
+    def n(self): pass
+    def o(self):
+        """Docstring
+
+not dedented"""
+        pass
+lambda_ = lambda x: x
+`, "\n")
+
+	tests := []struct {
+		name  string
+		start int
+		want  int
+	}{
+		{"one-line function", 1, 1},
+		{"function", 3, 4},
+		{"header with open brackets", 6, 17},
+		{"colons inside brackets", 9, 10},
+		{"one-line method", 12, 12},
+		{"docstring below the indentation", 13, 17},
+		{"not a block header", 18, 18},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindBlockEnd("python", source, tt.start); got != tt.want {
+				t.Errorf("FindBlockEnd(%d) = %d, want %d", tt.start, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindBlockEndRuby(t *testing.T) {
+	source := strings.Split(`class Point
+  def x; @x; end
+  def origin?; end
+  def area = w * h
+  def name=(value)
+    @name = value
+  end
+
+  def each
+    items.each do |item|
+      yield item
+    end
+  end
+end
+class Empty < StandardError; end
+`, "\n")
+
+	tests := []struct {
+		name  string
+		start int
+		want  int
+	}{
+		{"class", 1, 14},
+		{"one-line method with body", 2, 2},
+		{"one-line empty method", 3, 3},
+		{"endless method", 4, 4},
+		{"setter", 5, 7},
+		{"method with do block", 9, 13},
+		{"one-line class", 15, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindBlockEnd("ruby", source, tt.start); got != tt.want {
+				t.Errorf("FindBlockEnd(%d) = %d, want %d", tt.start, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindBlockEndBraces(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		source   string
+		start    int
+		want     int
+	}{
+		{
+			name:     "Kotlin abstract member",
+			language: "kotlin",
+			source: `abstract class Job {
+    abstract fun run()
+    fun stop() {
+        println("stop")
+    }
+}`,
+			start: 2,
+			want:  2,
+		},
+		{
+			name:     "Kotlin function after one without a body",
+			language: "kotlin",
+			source: `abstract class Job {
+    abstract fun run()
+    fun stop() {
+        println("stop")
+    }
+}`,
+			start: 3,
+			want:  5,
+		},
+		{
+			name:     "TypeScript declaration without semicolon",
+			language: "typescript",
+			source: `declare function load(path: string): Buffer
+
+function save(path: string) {
+  write(path)
+}`,
+			start: 1,
+			want:  1,
+		},
+		{
+			name:     "Kotlin signature over several lines",
+			language: "kotlin",
+			source: `fun connect(
+    host: String,
+    port: Int,
+) {
+    open(host, port)
+}`,
+			start: 1,
+			want:  6,
+		},
+		{
+			name:     "Java interface member",
+			language: "java",
+			source: `interface Shape {
+    double area();
+}`,
+			start: 1,
+			want:  3,
+		},
+		{
+			name:     "braces in strings and comments",
+			language: "javascript",
+			source: `function f() {
+  const s = "}"; // }
+  return s
+}`,
+			start: 1,
+			want:  4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindBlockEnd(tt.language, strings.Split(tt.source, "\n"), tt.start); got != tt.want {
+				t.Errorf("FindBlockEnd(%d) = %d, want %d", tt.start, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package finder
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// SkipReason describes why a file was left out of the output
+type SkipReason string
+
+const (
+	SkipBinary    SkipReason = "binary"
+	SkipGenerated SkipReason = "generated"
+	SkipLockfile  SkipReason = "lockfile"
+	SkipOversized SkipReason = "oversized"
+)
+
+// SkipReasons lists every category that can be allowed with Options.Allow
+var SkipReasons = []SkipReason{SkipBinary, SkipGenerated, SkipLockfile, SkipOversized}
+
+// SkippedFile records a file that was not read and why
+type SkippedFile struct {
+	Path   string
+	Reason SkipReason
+	Detail string
+}
+
+// ReadResult holds the files read along with the ones that were skipped
+type ReadResult struct {
+	Files   []FileContent
+	Skipped []SkippedFile
+}
+
+const (
+	sniffLength        = 8000 // Bytes inspected when looking for binary content
+	generatedScanLines = 40   // Lines searched for a generated-code marker
+	minifiedMinSize    = 4096 // Files smaller than this are never treated as minified
+	minifiedLineLength = 500  // Average line length above which a file counts as minified
+)
+
+// generatedMarker matches the Go convention for generated files, which
+// takes a line of its own
+var generatedMarker = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.\r?$`)
+
+// generatedTag matches the @generated tag other tools put in the comment
+// that opens a file
+var generatedTag = regexp.MustCompile(`@generated\b`)
+
+// lockfileNames are dependency lockfiles, which are rarely useful context
+var lockfileNames = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"bun.lockb":           true,
+	"go.sum":              true,
+	"Cargo.lock":          true,
+	"Gemfile.lock":        true,
+	"poetry.lock":         true,
+	"Pipfile.lock":        true,
+	"composer.lock":       true,
+	"packages.lock.json":  true,
+	"mix.lock":            true,
+	"flake.lock":          true,
+}
+
+// allowed reports whether a skip category has been overridden
+func (o Options) allowed(reason SkipReason) bool {
+	for _, allow := range o.Allow {
+		if allow == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSize decides whether a file should be skipped for its size alone,
+// before its content is read
+func checkSize(size int64, opts Options) (SkipReason, string) {
+	if opts.MaxFileSize > 0 && size > opts.MaxFileSize && !opts.allowed(SkipOversized) {
+		return SkipOversized, fmt.Sprintf("%d bytes exceeds limit of %d", size, opts.MaxFileSize)
+	}
+	return "", ""
+}
+
+// Classify inspects a file's name and content and returns the reason it
+// should be skipped, or an empty reason if it should be included. Categories
+// listed in opts.Allow are never reported.
+func Classify(path string, data []byte, opts Options) (SkipReason, string) {
+	if lockfileNames[filepath.Base(path)] && !opts.allowed(SkipLockfile) {
+		return SkipLockfile, "dependency lockfile"
+	}
+
+	if reason, detail := checkSize(int64(len(data)), opts); reason != "" {
+		return reason, detail
+	}
+
+	if isBinary(data) && !opts.allowed(SkipBinary) {
+		return SkipBinary, "non-text content"
+	}
+
+	if !opts.allowed(SkipGenerated) {
+		if detail := generatedDetail(path, data); detail != "" {
+			return SkipGenerated, detail
+		}
+	}
+
+	return "", ""
+}
+
+// isBinary sniffs the start of a file for NUL bytes or invalid UTF-8
+func isBinary(data []byte) bool {
+	sample := data
+	if len(sample) > sniffLength {
+		sample = sample[:sniffLength]
+		// Drop a multi-byte character cut off at the boundary
+		for i := len(sample) - 1; i >= 0 && i >= len(sample)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(sample[i]) {
+				if !utf8.FullRune(sample[i:]) {
+					sample = sample[:i]
+				}
+				break
+			}
+		}
+	}
+
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	return !utf8.Valid(sample)
+}
+
+// generatedDetail describes why content looks generated, or returns "" if it doesn't
+func generatedDetail(path string, data []byte) string {
+	head := data
+	if lines := bytes.SplitN(data, []byte("\n"), generatedScanLines+1); len(lines) > generatedScanLines {
+		head = data[:len(data)-len(lines[generatedScanLines])]
+	}
+	if generatedMarker.Match(head) || generatedTag.MatchString(leadingComment(path, head)) {
+		return "generated-code marker"
+	}
+
+	if len(data) >= minifiedMinSize {
+		lineCount := bytes.Count(data, []byte("\n")) + 1
+		if len(data)/lineCount > minifiedLineLength {
+			return "minified content"
+		}
+	}
+
+	return ""
+}
+
+// leadingComment returns the text of the comments that open a file, before
+// its first line of code. A shebang line or PHP open tag is passed over.
+func leadingComment(path string, head []byte) string {
+	lines := strings.Split(string(head), "\n")
+	if len(lines) > 0 && (strings.HasPrefix(lines[0], "#!") || strings.TrimSpace(lines[0]) == "<?php") {
+		lines = lines[1:]
+	}
+
+	var comment strings.Builder
+	for i, regions := range LexLines(DetectLanguageContent(path, head), lines) {
+		for _, region := range regions {
+			text := lines[i][region.Start:region.End]
+			switch {
+			case region.Kind == KindComment:
+				comment.WriteString(text)
+				comment.WriteByte('\n')
+			case strings.TrimSpace(text) != "":
+				return comment.String()
+			}
+		}
+	}
+	return comment.String()
+}
@@ -0,0 +1,87 @@
+package finder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	minified := "var a=" + strings.Repeat("1+", minifiedMinSize) + "1;"
+	// A three-byte character straddling the end of the sniffed sample
+	cutRune := strings.Repeat(strings.Repeat("a", 99)+"\n", sniffLength/100-1) + strings.Repeat("a", 99) + "€"
+
+	tests := []struct {
+		name   string
+		path   string
+		data   string
+		opts   Options
+		reason SkipReason
+	}{
+		{name: "plain code", path: "main.go", data: "package main\n"},
+		{name: "NUL byte", path: "image.png", data: "\x89PNG\r\n\x00\x00", reason: SkipBinary},
+		{name: "invalid UTF-8", path: "data.txt", data: "abc\xff\xfe", reason: SkipBinary},
+		{name: "character cut at the sniff boundary", path: "notes.txt", data: cutRune},
+		{name: "binary allowed", path: "image.png", data: "\x00", opts: Options{Allow: []SkipReason{SkipBinary}}},
+		{name: "minified single line", path: "app.min.js", data: minified, reason: SkipGenerated},
+		{name: "short single line", path: "one.js", data: "var a = 1;"},
+		{name: "lockfile", path: "web/package-lock.json", data: "{}\n", reason: SkipLockfile},
+		{name: "lockfile allowed", path: "go.sum", data: "x v1.0.0 h1:abc=\n", opts: Options{Allow: []SkipReason{SkipLockfile}}},
+		{name: "size at the limit", path: "a.go", data: "0123456789", opts: Options{MaxFileSize: 10}},
+		{name: "size over the limit", path: "a.go", data: "0123456789\n", opts: Options{MaxFileSize: 10}, reason: SkipOversized},
+		{name: "oversized allowed", path: "a.go", data: "0123456789\n", opts: Options{MaxFileSize: 10, Allow: []SkipReason{SkipOversized}}},
+		{
+			name:   "Go generated marker",
+			path:   "api.pb.go",
+			data:   "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n",
+			reason: SkipGenerated,
+		},
+		{
+			name:   "Go generated marker with CRLF",
+			path:   "api.pb.go",
+			data:   "// Code generated by stringer; DO NOT EDIT.\r\n\r\npackage api\r\n",
+			reason: SkipGenerated,
+		},
+		{
+			name: "Go marker quoted in a string",
+			path: "classify.go",
+			data: "package finder\n\nconst marker = \"// Code generated by x. DO NOT EDIT.\"\n",
+		},
+		{
+			name: "Go marker within a comment line",
+			path: "notes.go",
+			data: "package notes\n\n// Files that say Code generated by x. DO NOT EDIT. are skipped\n",
+		},
+		{
+			name:   "@generated in the opening comment",
+			path:   "Schema.php",
+			data:   "<?php\n/**\n * This file is @generated by codegen.\n */\nclass Schema {}\n",
+			reason: SkipGenerated,
+		},
+		{
+			name:   "@generated after a shebang",
+			path:   "build.js",
+			data:   "#!/usr/bin/env node\n// @generated\nmain();\n",
+			reason: SkipGenerated,
+		},
+		{
+			name: "@generated in a doc comment",
+			path: "gen.ts",
+			data: "import { x } from './x';\n\n/** Returns the @generated header for a file. */\nexport function header() {}\n",
+		},
+		{
+			name: "generated allowed",
+			path: "api.pb.go",
+			data: "// Code generated by protoc-gen-go. DO NOT EDIT.\n",
+			opts: Options{Allow: []SkipReason{SkipGenerated}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := Classify(tt.path, []byte(tt.data), tt.opts)
+			if reason != tt.reason {
+				t.Errorf("Classify(%q) = %q, want %q", tt.path, reason, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package finder
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// fileIDOf identifies a file by its fully resolved absolute path, as device
+// and inode numbers aren't available on this platform
+func fileIDOf(path string, info fs.FileInfo) (fileID, bool) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileID{}, false
+	}
+	resolved, err = filepath.Abs(resolved)
+	if err != nil {
+		return fileID{}, false
+	}
+	return fileID{path: resolved}, true
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package finder
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileIDOf identifies a file by its device and inode
+func fileIDOf(path string, info fs.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
@@ -1,11 +1,8 @@
 package finder
 
 import (
-	"os"
-	"path/filepath"
-	"strings"
-
-	"github.com/bmatcuk/doublestar/v4"
+	"context"
+	"io/fs"
 )
 
 // FileContent represents the content of a file
@@ -13,102 +10,92 @@ type FileContent struct {
 	Path     string
 	Content  string
 	Language string
+	Rev      string   // Git revision the content was read from, if not the working tree
+	Aliases  []string // Other paths the file was found under through symlinks
 }
 
-// FindFilesByGlob locates files matching the given glob pattern
-func FindFilesByGlob(basePath, pattern string) ([]string, error) {
-	var matches []string
+// FindFilesByGlob locates files matching the given glob pattern. Exclude and
+// language selectors in opts still apply; opts.Include is ignored.
+func FindFilesByGlob(src Source, pattern string, opts Options) ([]string, error) {
+	opts.Include = nil
+	return SelectFiles(src, []string{pattern}, opts)
+}
 
-	err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+// FindAllCodeFiles finds all code files in the given source
+func FindAllCodeFiles(src Source, opts Options) ([]string, error) {
+	return SelectFiles(src, nil, opts)
+}
 
-		relPath, err := filepath.Rel(basePath, path)
-		if err != nil {
-			return err
-		}
+// ReadFiles reads the content of the provided files. Binary, generated,
+// lockfile and oversized files are skipped unless allowed in opts, and files
+// that can't be read are reported to opts.Warnings when it is set.
+func ReadFiles(src Source, paths []string, opts Options) (ReadResult, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-		match, err := doublestar.Match(pattern, relPath)
-		if err != nil {
-			return err
-		}
+	return ReadStream(ctx, src, SendPaths(ctx, paths), opts)
+}
 
-		if match && !info.IsDir() {
-			matches = append(matches, path)
-		}
-		return nil
+// ReadStream reads files concurrently as their paths arrive, keeping them in
+// arrival order. Concurrency and memory use are bounded by opts.Limits.
+func ReadStream(ctx context.Context, src Source, paths <-chan string, opts Options) (ReadResult, error) {
+	var result ReadResult
+
+	outcomes, err := Pipeline(ctx, src, paths, opts.Limits, func(path string, info fs.FileInfo) (readOutcome, error) {
+		return readFile(src, path, info, opts)
 	})
+	if err != nil {
+		return result, err
+	}
 
-	return matches, err
-}
+	for _, outcome := range outcomes {
+		if outcome.skipped != nil {
+			result.Skipped = append(result.Skipped, *outcome.skipped)
+		} else if outcome.file != nil {
+			// The walk has finished by now, so every alias of the file is known
+			outcome.file.Aliases = opts.Aliases.For(outcome.file.Path)
+			result.Files = append(result.Files, *outcome.file)
+		}
+	}
 
-// FindAllCodeFiles finds all code files in the given directory
-func FindAllCodeFiles(basePath string) ([]string, error) {
-	return FindFilesByGlob(basePath, "**/*.{go,js,ts,py,java,c,cpp,h,hpp,cs,rb,php,pl,sh,html,css,md,json,yaml,yml}")
+	return result, nil
 }
 
-// ReadFiles reads the content of the provided files
-func ReadFiles(paths []string) ([]FileContent, error) {
-	var results []FileContent
+// readOutcome is a file that was read, the record of a skipped one, or
+// neither when the file failed and a warning was recorded
+type readOutcome struct {
+	file    *FileContent
+	skipped *SkippedFile
+}
 
-	for _, path := range paths {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
+// readFile reads and classifies a single file, given the info the pipeline
+// looked up for it. When that failed it is looked up again for the error.
+func readFile(src Source, path string, info fs.FileInfo, opts Options) (readOutcome, error) {
+	if info == nil {
+		var err error
+		if info, err = src.Stat(path); err != nil {
+			return readOutcome{}, opts.Warnings.Record(path, "stat", err)
 		}
+	}
 
-		language := DetectLanguage(path)
-		results = append(results, FileContent{
-			Path:     path,
-			Content:  string(data),
-			Language: language,
-		})
+	// Avoid reading files that are too large in the first place
+	if reason, detail := checkSize(info.Size(), opts); reason != "" {
+		return readOutcome{skipped: &SkippedFile{Path: path, Reason: reason, Detail: detail}}, nil
 	}
 
-	return results, nil
-}
+	data, err := src.ReadFile(path)
+	if err != nil {
+		return readOutcome{}, opts.Warnings.Record(path, "read", err)
+	}
 
-// detectLanguage determines the language of a file based on its extension
-func DetectLanguage(path string) string {
-	ext := strings.ToLower(filepath.Ext(path))
-
-	switch ext {
-	case ".go":
-		return "go"
-	case ".js":
-		return "javascript"
-	case ".ts":
-		return "typescript"
-	case ".py":
-		return "python"
-	case ".java":
-		return "java"
-	case ".c", ".h":
-		return "c"
-	case ".cpp", ".hpp":
-		return "cpp"
-	case ".cs":
-		return "csharp"
-	case ".rb":
-		return "ruby"
-	case ".php":
-		return "php"
-	case ".pl":
-		return "perl"
-	case ".sh":
-		return "bash"
-	case ".html":
-		return "html"
-	case ".css":
-		return "css"
-	case ".md":
-		return "markdown"
-	case ".json":
-		return "json"
-	case ".yaml", ".yml":
-		return "yaml"
-	default:
-		return "plaintext"
+	if reason, detail := Classify(path, data, opts); reason != "" {
+		return readOutcome{skipped: &SkippedFile{Path: path, Reason: reason, Detail: detail}}, nil
 	}
+
+	return readOutcome{file: &FileContent{
+		Path:     path,
+		Content:  string(data),
+		Language: DetectLanguageContent(path, data),
+		Rev:      src.Rev,
+	}}, nil
 }
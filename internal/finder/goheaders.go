@@ -0,0 +1,343 @@
+package finder
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// goParser extracts headers from Go source using go/parser, which gives
+// exact block ends and understands generics, multi-line signatures, grouped
+// declarations, aliases, embedded fields and doc comments
+type goParser struct {
+	fset *token.FileSet
+	src  []byte
+}
+
+// parseGoHeaders returns the headers of a Go file, or an error if it doesn't parse
+func parseGoHeaders(path string, src []byte) ([]HeaderElement, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	p := goParser{fset: fset, src: src}
+	headers := []HeaderElement{{
+		Type:      Package,
+		Name:      file.Name.Name,
+		LineNum:   p.line(file.Package),
+		EndLine:   p.line(file.Name.End()),
+		Signature: "package " + file.Name.Name,
+		Docstring: docText(file.Doc),
+	}}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			headers = append(headers, p.funcHeader(decl))
+		case *ast.GenDecl:
+			headers = append(headers, p.genDeclHeaders(decl)...)
+		}
+	}
+
+	return headers, nil
+}
+
+// funcHeader describes a function or method declaration
+func (p goParser) funcHeader(decl *ast.FuncDecl) HeaderElement {
+	header := HeaderElement{
+		Type:        Function,
+		Name:        decl.Name.Name,
+		LineNum:     p.line(decl.Pos()),
+		EndLine:     p.line(decl.End()),
+		Parameters:  p.fieldParams(decl.Type.Params),
+		ReturnTypes: p.fieldTypes(decl.Type.Results),
+		Docstring:   docText(decl.Doc),
+	}
+
+	// The signature is everything up to the body
+	end := decl.End()
+	if decl.Body != nil {
+		end = decl.Body.Lbrace
+	}
+	header.Signature = oneLine(p.text(decl.Pos(), end))
+
+	if decl.Recv != nil && len(decl.Recv.List) > 0 {
+		header.Type = Method
+		header.Parent = receiverName(decl.Recv.List[0].Type)
+	}
+
+	return header
+}
+
+// genDeclHeaders describes each spec of an import, const, var or type
+// declaration, which may be grouped in parentheses
+func (p goParser) genDeclHeaders(decl *ast.GenDecl) []HeaderElement {
+	if decl.Tok == token.IMPORT {
+		header := HeaderElement{
+			Type:      Import,
+			Name:      "import",
+			LineNum:   p.line(decl.Pos()),
+			EndLine:   p.line(decl.End()),
+			Docstring: docText(decl.Doc),
+		}
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.ImportSpec)
+			child := HeaderElement{
+				Type:    Import,
+				Name:    importPath(spec),
+				LineNum: p.line(spec.Pos()),
+			}
+			if spec.Name != nil {
+				child.Signature = spec.Name.Name // Use Signature to store alias
+			}
+			header.Children = append(header.Children, child)
+		}
+		return []HeaderElement{header}
+	}
+
+	var headers []HeaderElement
+	for _, spec := range decl.Specs {
+		// An ungrouped declaration carries its doc comment on the decl
+		doc := decl.Doc
+		if decl.Lparen.IsValid() {
+			doc = nil
+		}
+
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			if spec.Doc != nil {
+				doc = spec.Doc
+			}
+			headers = append(headers, p.typeHeader(spec, doc))
+
+		case *ast.ValueSpec:
+			if spec.Doc != nil {
+				doc = spec.Doc
+			}
+			elementType := Variable
+			if decl.Tok == token.CONST {
+				elementType = Constant
+			}
+			for i, name := range spec.Names {
+				header := HeaderElement{
+					Type:      elementType,
+					Name:      name.Name,
+					LineNum:   p.line(name.Pos()),
+					EndLine:   p.line(spec.End()),
+					Signature: oneLine(p.text(spec.Pos(), spec.End())),
+					Docstring: docText(doc),
+				}
+				if spec.Type != nil {
+					header.ValueType = p.expr(spec.Type)
+				}
+				if i < len(spec.Values) {
+					header.Value = p.value(spec.Values[i])
+				}
+				headers = append(headers, header)
+			}
+		}
+	}
+	return headers
+}
+
+// typeHeader describes a type declaration, listing the fields of structs and
+// the methods and embedded types of interfaces
+func (p goParser) typeHeader(spec *ast.TypeSpec, doc *ast.CommentGroup) HeaderElement {
+	header := HeaderElement{
+		Type:      NamedType,
+		Name:      spec.Name.Name,
+		LineNum:   p.line(spec.Pos()),
+		EndLine:   p.line(spec.End()),
+		Signature: oneLine(p.text(spec.Pos(), spec.End())),
+		Docstring: docText(doc),
+	}
+	if spec.Assign.IsValid() {
+		return header
+	}
+
+	switch typ := spec.Type.(type) {
+	case *ast.StructType:
+		header.Type = Struct
+		header.Signature = oneLine(p.text(spec.Pos(), typ.Fields.Opening))
+		for _, field := range typ.Fields.List {
+			header.Children = append(header.Children, p.memberHeaders(field, Field)...)
+		}
+	case *ast.InterfaceType:
+		header.Type = Interface
+		header.Signature = oneLine(p.text(spec.Pos(), typ.Methods.Opening))
+		for _, field := range typ.Methods.List {
+			elementType := Field // Embedded interfaces and type constraints
+			if _, ok := field.Type.(*ast.FuncType); ok {
+				elementType = Method
+			}
+			header.Children = append(header.Children, p.memberHeaders(field, elementType)...)
+		}
+	}
+
+	return header
+}
+
+// memberHeaders describes a struct field or interface method. Signature
+// holds the member as written, e.g. "Path string" or "Read(p []byte) error".
+func (p goParser) memberHeaders(field *ast.Field, elementType HeaderType) []HeaderElement {
+	typeText := p.expr(field.Type)
+
+	// Embedded fields and interfaces are named after their type
+	if len(field.Names) == 0 {
+		return []HeaderElement{{
+			Type:      elementType,
+			Name:      embeddedName(field.Type),
+			LineNum:   p.line(field.Pos()),
+			Signature: typeText,
+			ValueType: typeText,
+			Docstring: docText(field.Doc),
+		}}
+	}
+
+	var members []HeaderElement
+	for _, name := range field.Names {
+		member := HeaderElement{
+			Type:      elementType,
+			Name:      name.Name,
+			LineNum:   p.line(name.Pos()),
+			Signature: name.Name + " " + typeText,
+			ValueType: typeText,
+			Docstring: docText(field.Doc),
+		}
+		if elementType == Method {
+			member.Signature = name.Name + strings.TrimPrefix(typeText, "func")
+		}
+		members = append(members, member)
+	}
+	return members
+}
+
+// fieldParams lists the parameters of a function, one per name
+func (p goParser) fieldParams(fields *ast.FieldList) []ParameterInfo {
+	if fields == nil {
+		return nil
+	}
+
+	var params []ParameterInfo
+	for _, field := range fields.List {
+		typeText := p.expr(field.Type)
+		if len(field.Names) == 0 {
+			params = append(params, ParameterInfo{Type: typeText})
+			continue
+		}
+		for _, name := range field.Names {
+			params = append(params, ParameterInfo{Name: name.Name, Type: typeText})
+		}
+	}
+	return params
+}
+
+// fieldTypes lists the results of a function, as "name type" when named
+func (p goParser) fieldTypes(fields *ast.FieldList) []string {
+	var types []string
+	for _, param := range p.fieldParams(fields) {
+		if param.Name != "" {
+			types = append(types, param.Name+" "+param.Type)
+		} else {
+			types = append(types, param.Type)
+		}
+	}
+	return types
+}
+
+// line returns the 1-based line of a position
+func (p goParser) line(pos token.Pos) int {
+	return p.fset.Position(pos).Line
+}
+
+// text returns the source between two positions
+func (p goParser) text(start, end token.Pos) string {
+	from, to := p.fset.Position(start).Offset, p.fset.Position(end).Offset
+	if from < 0 || to > len(p.src) || from > to {
+		return ""
+	}
+	return strings.TrimSpace(string(p.src[from:to]))
+}
+
+// expr returns the source of an expression on a single line
+func (p goParser) expr(node ast.Expr) string {
+	return oneLine(p.text(node.Pos(), node.End()))
+}
+
+// value returns the source of a value, cut to its first line when it spans
+// several, such as a large composite literal
+func (p goParser) value(node ast.Expr) string {
+	text := p.text(node.Pos(), node.End())
+	if first, _, multiline := strings.Cut(text, "\n"); multiline {
+		return strings.TrimSpace(first) + " ..."
+	}
+	return text
+}
+
+// receiverName returns the type name of a method receiver such as *List[T]
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// embeddedName returns the field name of an embedded type: its type name
+// without pointer, package qualifier or type arguments
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(e.X)
+	case *ast.IndexListExpr:
+		return embeddedName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// importPath returns the unquoted path of an import spec
+func importPath(spec *ast.ImportSpec) string {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return spec.Path.Value
+	}
+	return path
+}
+
+// docText returns the text of a doc comment without comment markers
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Text())
+}
+
+// oneLine collapses source spanning several lines, such as a composite
+// literal or a wrapped signature, onto one line
+func oneLine(text string) string {
+	if !strings.Contains(text, "\n") {
+		return text
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
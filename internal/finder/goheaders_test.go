@@ -0,0 +1,173 @@
+package finder
+
+import (
+	"fmt"
+	"go/parser"
+	"reflect"
+	"testing"
+)
+
+// goHeadersSrc exercises the declarations go/parser handles and patterns don't:
+// generics, grouped and multi-line declarations, aliases and embedding
+const goHeadersSrc = `// Package list holds a generic list.
+package list
+
+import (
+	"fmt"
+	str "strings"
+)
+
+// Limits of a list
+const (
+	// MaxLen bounds the length
+	MaxLen   = 10
+	minLen int = 0
+)
+
+var defaults = map[string]int{
+	"a": 1,
+}
+
+// List is a list of T
+type List[T any] struct {
+	*fmt.Stringer
+	items    []T
+	len, cap int // Sizes
+}
+
+type Sizer interface {
+	fmt.Stringer
+	Size() int
+}
+
+type Alias = List[int]
+
+// Push adds v
+func (l *List[T]) Push(
+	v T,
+) (n int, err error) {
+	l.items = append(l.items, v)
+	return len(l.items), nil
+}
+
+func Join(a, b string) string { return str.Join([]string{a, b}, "") }
+`
+
+// summarize describes a header and its children on one line each
+func summarize(headers []HeaderElement) []string {
+	var lines []string
+	for _, h := range headers {
+		line := fmt.Sprintf("%s %s %d-%d %q", h.Type, h.Name, h.LineNum, h.EndLine, h.Signature)
+		if h.Parent != "" {
+			line += " parent=" + h.Parent
+		}
+		if h.Docstring != "" {
+			line += fmt.Sprintf(" doc=%q", h.Docstring)
+		}
+		lines = append(lines, line)
+		for _, child := range h.Children {
+			lines = append(lines, fmt.Sprintf("  %s %s %d %q", child.Type, child.Name, child.LineNum, child.Signature))
+		}
+	}
+	return lines
+}
+
+func TestParseGoHeaders(t *testing.T) {
+	headers, err := parseGoHeaders("list.go", []byte(goHeadersSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`Package list 2-2 "package list" doc="Package list holds a generic list."`,
+		`Import import 4-7 ""`,
+		`  Import fmt 5 ""`,
+		`  Import strings 6 "str"`,
+		`Constant MaxLen 12-12 "MaxLen   = 10" doc="MaxLen bounds the length"`,
+		`Constant minLen 13-13 "minLen int = 0"`,
+		`Variable defaults 16-18 "defaults = map[string]int{ \"a\": 1, }"`,
+		`Struct List 21-25 "List[T any] struct" doc="List is a list of T"`,
+		`  Field Stringer 22 "*fmt.Stringer"`,
+		`  Field items 23 "items []T"`,
+		`  Field len 24 "len int"`,
+		`  Field cap 24 "cap int"`,
+		`Interface Sizer 27-30 "Sizer interface"`,
+		`  Field Stringer 28 "fmt.Stringer"`,
+		`  Method Size 29 "Size() int"`,
+		`Type Alias 32-32 "Alias = List[int]"`,
+		`Method Push 35-40 "func (l *List[T]) Push( v T, ) (n int, err error)" parent=List doc="Push adds v"`,
+		`Function Join 42-42 "func Join(a, b string) string"`,
+	}
+	if got := summarize(headers); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGoHeaders() =\n%s\nwant\n%s", fmt.Sprintln(got), fmt.Sprintln(want))
+	}
+
+	byName := make(map[string]HeaderElement)
+	for _, h := range headers {
+		byName[h.Name] = h
+	}
+	push := byName["Push"]
+	if want := []ParameterInfo{{Name: "v", Type: "T"}}; !reflect.DeepEqual(push.Parameters, want) {
+		t.Errorf("Push parameters = %+v, want %+v", push.Parameters, want)
+	}
+	if want := []string{"n int", "err error"}; !reflect.DeepEqual(push.ReturnTypes, want) {
+		t.Errorf("Push results = %q, want %q", push.ReturnTypes, want)
+	}
+	if got := byName["defaults"].Value; got != "map[string]int{ ..." {
+		t.Errorf("defaults value = %q, want its first line", got)
+	}
+	if got := byName["minLen"].ValueType; got != "int" {
+		t.Errorf("minLen type = %q, want int", got)
+	}
+}
+
+func TestParseHeadersGoFallback(t *testing.T) {
+	// A file go/parser rejects still gets headers from the patterns
+	src := []byte("package broken\n\nfunc Half() {\n\tif {\n}\n")
+	if _, err := parseGoHeaders("broken.go", src); err == nil {
+		t.Fatal("parseGoHeaders() of invalid Go succeeded")
+	}
+
+	headers, err := ParseHeaders("broken.go", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, h := range headers {
+		if h.Type == Function && h.Name == "Half" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("ParseHeaders() = %+v, want function Half from the patterns", headers)
+	}
+}
+
+func TestReceiverAndEmbeddedNames(t *testing.T) {
+	tests := []struct {
+		expr     string
+		receiver string
+		embedded string
+	}{
+		{"T", "T", "T"},
+		{"*T", "T", "T"},
+		{"(*T)", "T", ""},
+		{"List[K]", "List", "List"},
+		{"*Map[K, V]", "Map", "Map"},
+		{"io.Reader", "", "Reader"},
+		{"*pkg.Set[int]", "", "Set"},
+	}
+
+	for _, tt := range tests {
+		expr, err := parser.ParseExpr(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := receiverName(expr); got != tt.receiver {
+			t.Errorf("receiverName(%s) = %q, want %q", tt.expr, got, tt.receiver)
+		}
+		if got := embeddedName(expr); got != tt.embedded {
+			t.Errorf("embeddedName(%s) = %q, want %q", tt.expr, got, tt.embedded)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package finder
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// goModuleDirective matches the module directive of a go.mod file
+var goModuleDirective = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+
+// GoModule is the Go module the files of a source belong to
+type GoModule struct {
+	Path string // Module path declared by go.mod
+	Dir  string // Directory of the source within the module, slash-separated; "." at its root
+}
+
+// FindGoModule finds the go.mod that governs a source: the one at its root,
+// or for sources on disk the nearest one in a directory above it. ok is false
+// when there is none.
+func FindGoModule(src Source) (module GoModule, ok bool) {
+	if data, err := fs.ReadFile(src.FS, "go.mod"); err == nil {
+		return parseGoModule(data, ".")
+	}
+	if src.Dir == "" {
+		return GoModule{}, false
+	}
+
+	dir, err := filepath.Abs(src.Dir)
+	if err != nil {
+		return GoModule{}, false
+	}
+	for sub := "."; ; {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return GoModule{}, false
+		}
+		sub = path.Join(filepath.Base(dir), sub)
+		dir = parent
+
+		if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			return parseGoModule(data, sub)
+		}
+	}
+}
+
+// parseGoModule reads the module path from the content of a go.mod
+func parseGoModule(data []byte, dir string) (GoModule, bool) {
+	m := goModuleDirective.FindSubmatch(data)
+	if m == nil {
+		return GoModule{}, false
+	}
+	return GoModule{Path: string(m[1]), Dir: dir}, true
+}
+
+// ImportPath returns the import path of the package in a directory, given by
+// its slash-separated name within the source
+func (m GoModule) ImportPath(dir string) string {
+	return path.Join(m.Path, m.Dir, dir)
+}
+
+// Contains reports whether an import path names a package of the module
+func (m GoModule) Contains(importPath string) bool {
+	return m.Path != "" && (importPath == m.Path || strings.HasPrefix(importPath, m.Path+"/"))
+}
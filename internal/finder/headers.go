@@ -2,6 +2,7 @@ package finder
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"regexp"
@@ -26,6 +27,7 @@ const (
 	Namespace HeaderType = "Namespace"
 	Module    HeaderType = "Module"
 	Define    HeaderType = "Define"
+	NamedType HeaderType = "Type" // Other type declarations, such as Go's "type ID int" or aliases
 )
 
 // HeaderElement represents a structural element in a file
@@ -142,6 +144,16 @@ var languagePatternRegistry = map[string][]LanguagePattern{
 		},
 	},
 	"java": {
+		{
+			ElementType: Package,
+			Pattern:     regexp.MustCompile(`^package\s+([A-Za-z0-9_.]+)\s*;`),
+			NameGroup:   1,
+		},
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^import\s+(?:static\s+)?([A-Za-z0-9_.*]+)\s*;`),
+			NameGroup:   1,
+		},
 		{
 			ElementType: Class,
 			Pattern:     regexp.MustCompile(`\b(public|private|protected)?\s+class\s+([A-Za-z0-9_]+)`),
@@ -162,6 +174,21 @@ var languagePatternRegistry = map[string][]LanguagePattern{
 		},
 	},
 	"javascript": {
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^import\s+(.+?);?$`), // Specifier and bindings; "{" when they continue on later lines
+			NameGroup:   1,
+		},
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^export\s+((?:type\s+)?(?:\*|\{).*?\bfrom\s*['"][^'"]+['"]);?$`), // Re-exports
+			NameGroup:   1,
+		},
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^((?:(?:const|let|var)\s+[^=]+=\s*)?require\s*\(\s*['"][^'"]+['"]\s*\)).*$`),
+			NameGroup:   1,
+		},
 		{
 			ElementType: Function,
 			Pattern:     regexp.MustCompile(`function\s+([A-Za-z0-9_]+)\s*\(`),
@@ -184,6 +211,21 @@ var languagePatternRegistry = map[string][]LanguagePattern{
 		},
 	},
 	"typescript": {
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^import\s+(.+?);?$`), // Specifier and bindings; "{" when they continue on later lines
+			NameGroup:   1,
+		},
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^export\s+((?:type\s+)?(?:\*|\{).*?\bfrom\s*['"][^'"]+['"]);?$`), // Re-exports
+			NameGroup:   1,
+		},
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^((?:(?:const|let|var)\s+[^=]+=\s*)?require\s*\(\s*['"][^'"]+['"]\s*\)).*$`),
+			NameGroup:   1,
+		},
 		{
 			ElementType: Function,
 			Pattern:     regexp.MustCompile(`function\s+([A-Za-z0-9_]+)\s*\(`),
@@ -206,6 +248,11 @@ var languagePatternRegistry = map[string][]LanguagePattern{
 		},
 	},
 	"c": {
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^#\s*include\s*([<"][^>"]+[>"])`),
+			NameGroup:   1,
+		},
 		{
 			ElementType: Function,
 			Pattern:     regexp.MustCompile(`^[A-Za-z0-9_]+\s+([A-Za-z0-9_]+)\s*\(`),
@@ -223,6 +270,11 @@ var languagePatternRegistry = map[string][]LanguagePattern{
 		},
 	},
 	"cpp": {
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^#\s*include\s*([<"][^>"]+[>"])`),
+			NameGroup:   1,
+		},
 		{
 			ElementType: Function,
 			Pattern:     regexp.MustCompile(`^[A-Za-z0-9_:<>]+\s+([A-Za-z0-9_]+)\s*\(`),
@@ -245,6 +297,11 @@ var languagePatternRegistry = map[string][]LanguagePattern{
 		},
 	},
 	"csharp": {
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^using\s+(?:static\s+)?([A-Za-z0-9_.]+(?:\s*=\s*[A-Za-z0-9_.]+)?)\s*;`),
+			NameGroup:   1,
+		},
 		{
 			ElementType: Class,
 			Pattern:     regexp.MustCompile(`\b(public|private|protected|internal)?\s+class\s+([A-Za-z0-9_]+)`),
@@ -287,6 +344,16 @@ var languagePatternRegistry = map[string][]LanguagePattern{
 		},
 	},
 	"php": {
+		{
+			ElementType: Package,
+			Pattern:     regexp.MustCompile(`^namespace\s+([A-Za-z0-9_\\]+)\s*;`),
+			NameGroup:   1,
+		},
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^use\s+([A-Za-z0-9_\\]+)`),
+			NameGroup:   1,
+		},
 		{
 			ElementType: Class,
 			Pattern:     regexp.MustCompile(`class\s+([A-Za-z0-9_]+)`),
@@ -304,6 +371,73 @@ var languagePatternRegistry = map[string][]LanguagePattern{
 			ScopeGroup:  1,
 		},
 	},
+	"rust": {
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^(?:pub(?:\([a-z]+\))?\s+)?use\s+([^;]+);?`),
+			NameGroup:   1,
+		},
+		{
+			ElementType: Function,
+			Pattern:     regexp.MustCompile(`^(pub(?:\([a-z]+\))?)?\s*(?:async\s+)?(?:unsafe\s+)?fn\s+([A-Za-z0-9_]+)`),
+			NameGroup:   2,
+			ScopeGroup:  1,
+		},
+		{
+			ElementType: Struct,
+			Pattern:     regexp.MustCompile(`^(pub(?:\([a-z]+\))?)?\s*struct\s+([A-Za-z0-9_]+)`),
+			NameGroup:   2,
+			ScopeGroup:  1,
+		},
+		{
+			ElementType: Enum,
+			Pattern:     regexp.MustCompile(`^(pub(?:\([a-z]+\))?)?\s*enum\s+([A-Za-z0-9_]+)`),
+			NameGroup:   2,
+			ScopeGroup:  1,
+		},
+		{
+			ElementType: Interface,
+			Pattern:     regexp.MustCompile(`^(pub(?:\([a-z]+\))?)?\s*trait\s+([A-Za-z0-9_]+)`),
+			NameGroup:   2,
+			ScopeGroup:  1,
+		},
+		{
+			ElementType: Module,
+			Pattern:     regexp.MustCompile(`^(pub(?:\([a-z]+\))?)?\s*mod\s+([A-Za-z0-9_]+)`),
+			NameGroup:   2,
+			ScopeGroup:  1,
+		},
+	},
+	"kotlin": {
+		{
+			ElementType: Package,
+			Pattern:     regexp.MustCompile(`^package\s+([A-Za-z0-9_.]+)`),
+			NameGroup:   1,
+		},
+		{
+			ElementType: Import,
+			Pattern:     regexp.MustCompile(`^import\s+([A-Za-z0-9_.*]+)`),
+			NameGroup:   1,
+		},
+		{
+			ElementType: Function,
+			Pattern:     regexp.MustCompile(`\b(public|private|protected|internal)?\s*(?:suspend\s+|override\s+|inline\s+)*fun\s+(?:<[^>]*>\s*)?(?:[A-Za-z0-9_]+\.)?([A-Za-z0-9_]+)\s*\(`),
+			NameGroup:   2,
+			ScopeGroup:  1,
+		},
+		{
+			ElementType: Class,
+			Pattern:     regexp.MustCompile(`\b(public|private|protected|internal)?\s*(?:data\s+|sealed\s+|abstract\s+|open\s+|enum\s+)*(?:class|object)\s+([A-Za-z0-9_]+)`),
+			NameGroup:   2,
+			ScopeGroup:  1,
+		},
+		{
+			ElementType: Interface,
+			Pattern:     regexp.MustCompile(`\b(public|private|protected|internal)?\s*interface\s+([A-Za-z0-9_]+)`),
+			NameGroup:   2,
+			ScopeGroup:  1,
+		},
+	},
 }
 
 // defaultPatterns contains generic patterns that might work across languages
@@ -320,15 +454,96 @@ var defaultPatterns = []LanguagePattern{
 	},
 }
 
+// statementKeyword matches keywords that make a "type name(" shaped line a
+// statement, such as "return foo(x);" or "throw new Error(msg);"
+var statementKeyword = regexp.MustCompile(`\b(new|return|throw|await|yield|else|case|goto|delete)\s`)
+
+// controlNames are keywords that the looser method patterns can mistake for names
+var controlNames = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"foreach": true, "using": true, "lock": true, "sizeof": true,
+}
+
+// isStatement reports whether a function or method match is really a call
+// or control statement
+func isStatement(elementType HeaderType, match, name string) bool {
+	if elementType != Function && elementType != Method {
+		return false
+	}
+	return controlNames[name] || statementKeyword.MatchString(match)
+}
+
+// includeDirective matches a C or C++ #include line
+var includeDirective = regexp.MustCompile(`^#\s*include\b`)
+
+// isInclude reports whether a line is a C or C++ #include. Other lines
+// starting with "#" are skipped as comments or preprocessor directives, so
+// only includes reach the header patterns.
+func isInclude(language, line string) bool {
+	return (language == "c" || language == "cpp") && includeDirective.MatchString(line)
+}
+
+// jsImportSpecifier matches the module named by an import, re-export or
+// require call
+var jsImportSpecifier = regexp.MustCompile(`(?:\bfrom|^import|\brequire\s*\()\s*['"]([^'"]+)['"]`)
+
+// addImportSpecifier adds the file or module a JavaScript, TypeScript, C or
+// C++ import names to its header as a child. C includes keep their quotes or
+// angle brackets, which tell project headers from system ones. A JavaScript
+// or TypeScript import spanning several lines is followed to its end.
+func addImportSpecifier(header *HeaderElement, language string, lines, masked []string) {
+	switch language {
+	case "c", "cpp":
+		header.Children = append(header.Children, HeaderElement{
+			Type:    Import,
+			Name:    header.Name,
+			LineNum: header.LineNum,
+		})
+
+	case "javascript", "typescript":
+		end := header.LineNum
+		depth := 0
+		for i := header.LineNum - 1; i < len(masked); i++ {
+			depth += strings.Count(masked[i], "{") - strings.Count(masked[i], "}")
+			end = i + 1
+			if depth <= 0 {
+				break
+			}
+		}
+
+		statement := strings.TrimSpace(strings.Join(lines[header.LineNum-1:end], "\n"))
+		if m := jsImportSpecifier.FindStringSubmatch(statement); m != nil {
+			header.EndLine = end
+			header.Children = append(header.Children, HeaderElement{
+				Type:    Import,
+				Name:    m[1],
+				LineNum: header.LineNum,
+			})
+		}
+	}
+}
+
 // CollectHeaders extracts headers (functions, classes, etc.) from a file
 func CollectHeaders(path string) ([]HeaderElement, error) {
-	file, err := os.Open(path)
+	fileContents, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
-	defer file.Close()
 
-	language := DetectLanguage(path)
+	return ParseHeaders(path, fileContents)
+}
+
+// ParseHeaders extracts headers from file contents, using path to detect the
+// language. Go files are parsed with go/parser, falling back to the patterns
+// when they don't compile.
+func ParseHeaders(path string, fileContents []byte) ([]HeaderElement, error) {
+	language := DetectLanguageContent(path, fileContents)
+
+	if language == "go" {
+		if headers, err := parseGoHeaders(path, fileContents); err == nil {
+			return headers, nil
+		}
+	}
 
 	// Get patterns for this language
 	patterns, exists := languagePatternRegistry[language]
@@ -338,7 +553,7 @@ func CollectHeaders(path string) ([]HeaderElement, error) {
 	}
 
 	var headers []HeaderElement
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(fileContents))
 	lineNum := 0
 
 	// Variables to track block elements (like imports, structs)
@@ -347,12 +562,10 @@ func CollectHeaders(path string) ([]HeaderElement, error) {
 	var braceCount int
 	var currentClass string
 
-	// Reread the file contents for block processing
-	fileContents, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file for block processing: %w", err)
-	}
+	// Split the contents for block processing, with a copy whose comments and
+	// strings are blanked out for finding where blocks end
 	lines := strings.Split(string(fileContents), "\n")
+	masked := MaskCode(language, lines)
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -362,7 +575,7 @@ func CollectHeaders(path string) ([]HeaderElement, error) {
 		trimmedLine := strings.TrimSpace(line)
 		if trimmedLine == "" ||
 			strings.HasPrefix(trimmedLine, "//") ||
-			strings.HasPrefix(trimmedLine, "#") ||
+			(strings.HasPrefix(trimmedLine, "#") && !isInclude(language, trimmedLine)) ||
 			strings.HasPrefix(trimmedLine, "/*") {
 			continue
 		}
@@ -402,10 +615,19 @@ func CollectHeaders(path string) ([]HeaderElement, error) {
 			continue
 		}
 
+		// Lines inside block comments and multi-line strings declare nothing
+		if lineNum <= len(masked) && strings.TrimSpace(masked[lineNum-1]) == "" {
+			continue
+		}
+
 		// Try each pattern for this language
 		for _, pattern := range patterns {
 			matches := pattern.Pattern.FindStringSubmatch(trimmedLine)
 			if len(matches) > pattern.NameGroup && pattern.NameGroup > 0 {
+				if isStatement(pattern.ElementType, matches[0], matches[pattern.NameGroup]) {
+					continue
+				}
+
 				header := HeaderElement{
 					Type:    pattern.ElementType,
 					Name:    matches[pattern.NameGroup],
@@ -534,6 +756,12 @@ func CollectHeaders(path string) ([]HeaderElement, error) {
 					continue
 				}
 
+				// Other imports name the module they import as a child, as Go
+				// and Python imports do
+				if header.Type == Import && len(header.Children) == 0 {
+					addImportSpecifier(&header, language, lines, masked)
+				}
+
 				// Extract docstrings for supported languages
 				if language == "python" {
 					extractDocstring(&header, lineNum, lines)
@@ -544,6 +772,11 @@ func CollectHeaders(path string) ([]HeaderElement, error) {
 					extractMembers(&header, lineNum, lines)
 				}
 
+				// Record where function and type bodies end
+				if IsBlockType(header.Type) {
+					header.EndLine = findBlockEnd(language, masked, lineNum)
+				}
+
 				headers = append(headers, header)
 				break // Stop after first matching pattern
 			}
@@ -1001,16 +1234,24 @@ func FormatHeaders(headers []HeaderElement, includeDocstrings bool) string {
 				builder.WriteString(fmt.Sprintf("    %s\n", header.Name))
 			}
 
-		case Struct, Class:
+		case Struct, Class, Interface:
 			if header.Scope != "" {
 				builder.WriteString(fmt.Sprintf("Line %d: %s %s: %s\n", header.LineNum, header.Scope, header.Type, header.Name))
 			} else {
 				builder.WriteString(fmt.Sprintf("Line %d: %s: %s\n", header.LineNum, header.Type, header.Name))
 			}
 			for _, child := range header.Children {
-				builder.WriteString(fmt.Sprintf("    %s\n", child.Name))
+				// Members parsed from Go carry their full declaration
+				if child.Signature != "" {
+					builder.WriteString(fmt.Sprintf("    %s\n", child.Signature))
+				} else {
+					builder.WriteString(fmt.Sprintf("    %s\n", child.Name))
+				}
 			}
 
+		case NamedType:
+			builder.WriteString(fmt.Sprintf("Line %d: %s: %s\n", header.LineNum, header.Type, header.Signature))
+
 		default:
 			if header.Scope != "" {
 				builder.WriteString(fmt.Sprintf("Line %d: %s %s: %s\n", header.LineNum, header.Scope, header.Type, header.Name))
@@ -0,0 +1,49 @@
+package finder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsInclude(t *testing.T) {
+	tests := []struct {
+		language string
+		line     string
+		want     bool
+	}{
+		{"c", `#include <stdio.h>`, true},
+		{"c", `#include "parse.h"`, true},
+		{"cpp", `#  include <vector>`, true},
+		{"c", `#define include 1`, false},
+		{"c", `#includes`, false},
+		{"c", `#pragma once`, false},
+		{"c", `// #include "x.h"`, false},
+		{"python", `#include "x.h"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := isInclude(tt.language, tt.line); got != tt.want {
+			t.Errorf("isInclude(%s, %q) = %v, want %v", tt.language, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseHeadersCIncludes(t *testing.T) {
+	src := []byte("#ifndef PARSE_H\n#define PARSE_H\n#include <stdio.h>\n#include \"parse.h\"\n\nint parse(void);\n#endif\n")
+	headers, err := ParseHeaders("parse.c", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, header := range headers {
+		if header.Type == Import {
+			for _, child := range header.Children {
+				got = append(got, child.Name)
+			}
+		}
+	}
+	if want := []string{"<stdio.h>", `"parse.h"`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHeaders() includes = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,269 @@
+package finder
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// ignoreFileNames lists the per-directory ignore files, lowest precedence first
+var ignoreFileNames = []string{".gitignore", ".codeclipignore"}
+
+// ignoreRule is a single parsed line from an ignore file
+type ignoreRule struct {
+	pattern string // doublestar pattern relative to the directory holding the rule
+	negate  bool   // rule started with "!" and re-includes matching paths
+	dirOnly bool   // rule ended with "/" and only matches directories
+}
+
+// ignoreRuleSet holds the rules loaded from one ignore file
+type ignoreRuleSet struct {
+	dir    string // walk-relative directory the rules apply to ("" for the walk root and above)
+	prefix string // prepended to the relative path for rules loaded above the walk root
+	rules  []ignoreRule
+}
+
+// IgnoreMatcher decides whether paths should be skipped according to
+// .gitignore, .codeclipignore and .git/info/exclude rules
+type IgnoreMatcher struct {
+	sets []ignoreRuleSet
+}
+
+// NewIgnoreMatcher creates a matcher for a walk over src. For sources on
+// disk, rules from .git/info/exclude and from ignore files in the directories
+// between the repository root and the source are loaded up front; ignore files
+// inside the source are loaded as the walk reaches them via LoadDir.
+func NewIgnoreMatcher(src Source) *IgnoreMatcher {
+	m := &IgnoreMatcher{}
+	if src.Dir == "" {
+		return m
+	}
+
+	absBase, err := filepath.Abs(src.Dir)
+	if err != nil {
+		return m
+	}
+
+	repoRoot, gitDir := findGitDir(absBase)
+	if repoRoot == "" {
+		return m
+	}
+
+	relBase, err := filepath.Rel(repoRoot, absBase)
+	if err != nil {
+		return m
+	}
+	relBase = filepath.ToSlash(relBase)
+
+	// .git/info/exclude has the lowest precedence of all rule sources
+	if rules := readIgnoreFile(os.DirFS(gitDir), "info/exclude"); len(rules) > 0 {
+		m.sets = append(m.sets, ignoreRuleSet{prefix: prefixFor(relBase), rules: rules})
+	}
+
+	// Load ignore files from the repository root down to (but excluding) the source directory
+	if relBase == "." {
+		return m
+	}
+	dir := repoRoot
+	rel := relBase
+	for {
+		dirFS := os.DirFS(dir)
+		for _, name := range ignoreFileNames {
+			if rules := readIgnoreFile(dirFS, name); len(rules) > 0 {
+				m.sets = append(m.sets, ignoreRuleSet{prefix: prefixFor(rel), rules: rules})
+			}
+		}
+
+		first, rest, found := strings.Cut(rel, "/")
+		if !found {
+			break
+		}
+		dir = filepath.Join(dir, first)
+		rel = rest
+	}
+
+	return m
+}
+
+// LoadDir reads the ignore files in a directory the walk has entered. relDir
+// is the directory's slash-separated name within fsys.
+func (m *IgnoreMatcher) LoadDir(fsys fs.FS, relDir string) {
+	if relDir == "." {
+		relDir = ""
+	}
+	for _, name := range ignoreFileNames {
+		if rules := readIgnoreFile(fsys, path.Join(relDir, name)); len(rules) > 0 {
+			m.sets = append(m.sets, ignoreRuleSet{dir: relDir, rules: rules})
+		}
+	}
+}
+
+// Match reports whether the slash-separated, walk-relative path is ignored
+func (m *IgnoreMatcher) Match(relPath string, isDir bool) bool {
+	// Later and deeper rule sets take precedence, as does the last matching rule in a file
+	for i := len(m.sets) - 1; i >= 0; i-- {
+		set := m.sets[i]
+
+		target := relPath
+		if set.dir != "" {
+			if !strings.HasPrefix(relPath, set.dir+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(relPath, set.dir+"/")
+		}
+		target = set.prefix + target
+
+		for j := len(set.rules) - 1; j >= 0; j-- {
+			rule := set.rules[j]
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if match, _ := doublestar.Match(rule.pattern, target); match {
+				return !rule.negate
+			}
+		}
+	}
+
+	return false
+}
+
+// isGitDir reports whether a slash-separated path names a .git directory,
+// which is never part of the project whether or not ignore rules apply
+func isGitDir(relPath string) bool {
+	return path.Base(relPath) == ".git"
+}
+
+// prefixFor returns the prefix needed to express walk-relative paths relative
+// to a directory rel levels above the walk root
+func prefixFor(rel string) string {
+	if rel == "" || rel == "." {
+		return ""
+	}
+	return rel + "/"
+}
+
+// findGitDir walks up from dir looking for a repository, returning the
+// working tree root and the directory holding info/exclude
+func findGitDir(dir string) (root, gitDir string) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		info, err := os.Stat(dotGit)
+		if err == nil {
+			if info.IsDir() {
+				return dir, dotGit
+			}
+			// Worktrees and submodules use a file pointing at the real git dir
+			return dir, resolveGitFile(dir, dotGit)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// resolveGitFile follows a "gitdir:" file to the directory shared by all worktrees
+func resolveGitFile(root, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !found {
+		return ""
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(root, gitDir)
+	}
+
+	// Linked worktrees keep info/exclude in the common directory
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+		return commonDir
+	}
+
+	return gitDir
+}
+
+// readIgnoreFile parses an ignore file, returning nil if it doesn't exist
+func readIgnoreFile(fsys fs.FS, name string) []ignoreRule {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil
+	}
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// parseIgnoreLine converts one line of gitignore syntax into a rule
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+
+	line = strings.TrimSuffix(line, "\r")
+	line = trimUnescapedTrailingSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to its directory
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	// Braces are literal in gitignore syntax but special to doublestar
+	line = strings.NewReplacer("{", `\{`, "}", `\}`).Replace(line)
+
+	if anchored || strings.HasPrefix(line, "**/") {
+		rule.pattern = line
+	} else {
+		rule.pattern = "**/" + line
+	}
+
+	return rule, true
+}
+
+// trimUnescapedTrailingSpace removes trailing spaces that aren't backslash-escaped
+func trimUnescapedTrailingSpace(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-2] + " "
+	}
+	return line
+}
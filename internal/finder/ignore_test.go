@@ -0,0 +1,130 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want ignoreRule
+		ok   bool
+	}{
+		{"", ignoreRule{}, false},
+		{"# comment", ignoreRule{}, false},
+		{"*.log", ignoreRule{pattern: "**/*.log"}, true},
+		{"*.log   ", ignoreRule{pattern: "**/*.log"}, true},
+		{`trailing\ `, ignoreRule{pattern: "**/trailing "}, true},
+		{"!keep.log", ignoreRule{pattern: "**/keep.log", negate: true}, true},
+		{`\!bang`, ignoreRule{pattern: "**/!bang"}, true},
+		{`\#hash`, ignoreRule{pattern: "**/#hash"}, true},
+		{"/build", ignoreRule{pattern: "build"}, true},
+		{"docs/out", ignoreRule{pattern: "docs/out"}, true},
+		{"build/", ignoreRule{pattern: "**/build", dirOnly: true}, true},
+		{"/build/", ignoreRule{pattern: "build", dirOnly: true}, true},
+		{"**/gen", ignoreRule{pattern: "**/gen"}, true},
+		{"{a}.txt", ignoreRule{pattern: `**/\{a\}.txt`}, true},
+		{"/", ignoreRule{}, false},
+		{"line\r", ignoreRule{pattern: "**/line"}, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseIgnoreLine(tt.line)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseIgnoreLine(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":          {Data: []byte("*.log\n!keep.log\n/build\ncache/\ndocs/out\n")},
+		"sub/.gitignore":      {Data: []byte("!debug.log\n/local\n")},
+		"sub/.codeclipignore": {Data: []byte("debug.log\n")},
+	}
+
+	m := &IgnoreMatcher{}
+	m.LoadDir(fsys, ".")
+	m.LoadDir(fsys, "sub")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		// Unanchored patterns match at any depth
+		{"a.log", false, true},
+		{"deep/a/b.log", false, true},
+		{"a.go", false, false},
+
+		// A later negation re-includes
+		{"keep.log", false, false},
+		{"deep/keep.log", false, false},
+
+		// A leading or inner slash anchors to the ignore file's directory
+		{"build", true, true},
+		{"build", false, true},
+		{"pkg/build", true, false},
+		{"docs/out", false, true},
+		{"pkg/docs/out", false, false},
+
+		// A trailing slash only matches directories
+		{"cache", true, true},
+		{"pkg/cache", true, true},
+		{"cache", false, false},
+
+		// Nested files apply below their directory, and take precedence
+		{"sub/local", false, true},
+		{"local", false, false},
+		{"sub/deeper/local", false, false},
+		{"sub/other.log", false, true},
+		{"sub/debug.log", false, true}, // .codeclipignore overrides the sibling .gitignore
+		{"debug.log", false, true},
+	}
+
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreMatcherAboveSource(t *testing.T) {
+	// Rules in the repository root and .git/info/exclude apply to a source
+	// in a subdirectory, relative to where they are written
+	root := t.TempDir()
+	files := map[string]string{
+		".git/info/exclude": "*.tmp\n",
+		".gitignore":        "/app/gen\nsecret.txt\n",
+		"app/main.go":       "package main\n",
+	}
+	for name, data := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewIgnoreMatcher(DirSource(filepath.Join(root, "app")))
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"gen", true},
+		{"pkg/gen", false},
+		{"secret.txt", true},
+		{"pkg/x.tmp", true},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, false); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package finder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Confidence levels for each detection layer, from most to least reliable
+const (
+	ConfidenceFilename  = 1.0
+	ConfidenceModeline  = 0.95
+	ConfidenceExtension = 0.85
+	ConfidenceShebang   = 0.75
+	ConfidenceUnknown   = 0.0
+)
+
+// detectionHeadSize is how much of a file is read to look for shebangs and modelines
+const detectionHeadSize = 1024
+
+// modelineLines is how many lines at each end of a file are checked for modelines
+const modelineLines = 5
+
+// Detection is the result of language detection
+type Detection struct {
+	Language   string
+	Confidence float64
+	Source     string // Layer that decided: filename, modeline, extension, shebang or none
+}
+
+// filenameLanguages maps exact file names to languages
+var filenameLanguages = map[string]string{
+	"Makefile":       "makefile",
+	"GNUmakefile":    "makefile",
+	"makefile":       "makefile",
+	"Dockerfile":     "dockerfile",
+	"Containerfile":  "dockerfile",
+	"Jenkinsfile":    "groovy",
+	"Rakefile":       "ruby",
+	"Gemfile":        "ruby",
+	"Podfile":        "ruby",
+	"Vagrantfile":    "ruby",
+	"CMakeLists.txt": "cmake",
+	"BUILD":          "starlark",
+	"BUILD.bazel":    "starlark",
+	"WORKSPACE":      "starlark",
+	"Tiltfile":       "starlark",
+	"go.mod":         "gomod",
+	".bashrc":        "bash",
+	".bash_profile":  "bash",
+	".zshrc":         "bash",
+	".profile":       "bash",
+}
+
+// extensionLanguages maps lower-case file extensions to languages
+var extensionLanguages = map[string]string{
+	".go":    "go",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".cjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".mts":   "typescript",
+	".cts":   "typescript",
+	".py":    "python",
+	".pyi":   "python",
+	".java":  "java",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".cc":    "cpp",
+	".cxx":   "cpp",
+	".hpp":   "cpp",
+	".hh":    "cpp",
+	".hxx":   "cpp",
+	".cs":    "csharp",
+	".rb":    "ruby",
+	".php":   "php",
+	".pl":    "perl",
+	".pm":    "perl",
+	".sh":    "bash",
+	".bash":  "bash",
+	".zsh":   "bash",
+	".rs":    "rust",
+	".kt":    "kotlin",
+	".kts":   "kotlin",
+	".swift": "swift",
+	".scala": "scala",
+	".lua":   "lua",
+	".html":  "html",
+	".htm":   "html",
+	".css":   "css",
+	".scss":  "scss",
+	".md":    "markdown",
+	".json":  "json",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".toml":  "toml",
+	".xml":   "xml",
+	".sql":   "sql",
+	".proto": "protobuf",
+	".mk":    "makefile",
+}
+
+// interpreterLanguages maps shebang interpreters to languages
+var interpreterLanguages = map[string]string{
+	"sh":      "bash",
+	"bash":    "bash",
+	"zsh":     "bash",
+	"dash":    "bash",
+	"ksh":     "bash",
+	"python":  "python",
+	"python2": "python",
+	"python3": "python",
+	"node":    "javascript",
+	"nodejs":  "javascript",
+	"deno":    "typescript",
+	"ts-node": "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+}
+
+var (
+	// vimModeline matches "vim: set ft=python:" and "vi: filetype=ruby"
+	vimModeline = regexp.MustCompile(`\b(?:vim?|ex):.*\b(?:ft|filetype|syntax)=([A-Za-z0-9_+#-]+)`)
+
+	// emacsModeline matches "-*- mode: python -*-" and "-*- python -*-"
+	emacsModeline = regexp.MustCompile(`-\*-\s*(?:.*;\s*)?(?:mode:\s*)?([A-Za-z0-9_+#-]+)\s*(?:;.*)?-\*-`)
+)
+
+// LanguageConfig holds user additions to the detection tables
+type LanguageConfig struct {
+	Filenames    map[string]string `json:"filenames"`
+	Extensions   map[string]string `json:"extensions"`
+	Interpreters map[string]string `json:"interpreters"`
+}
+
+// LoadLanguageConfig reads a JSON LanguageConfig file and merges it into the
+// detection tables, overriding built-in entries. A missing file is not an error.
+func LoadLanguageConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var config LanguageConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("invalid language config %s: %w", path, err)
+	}
+
+	RegisterLanguages(config)
+	return nil
+}
+
+// RegisterLanguages merges additional filename, extension and interpreter
+// mappings into the detection tables
+func RegisterLanguages(config LanguageConfig) {
+	for name, lang := range config.Filenames {
+		filenameLanguages[name] = NormalizeLanguage(lang)
+	}
+	for ext, lang := range config.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensionLanguages[strings.ToLower(ext)] = NormalizeLanguage(lang)
+	}
+	for interpreter, lang := range config.Interpreters {
+		interpreterLanguages[interpreter] = NormalizeLanguage(lang)
+	}
+}
+
+// DetectLanguage determines the language of a file from its name, and from
+// the start of its content when the file can be read
+func DetectLanguage(path string) string {
+	return Detect(path, readHead(path)).Language
+}
+
+// DetectLanguageContent determines the language of a file whose content is already loaded
+func DetectLanguageContent(path string, content []byte) string {
+	return Detect(path, content).Language
+}
+
+// Detect runs the detection layers in order of reliability: exact file name,
+// editor modeline, extension and shebang. content may be nil or only the
+// start of the file.
+func Detect(path string, content []byte) Detection {
+	base := filepath.Base(path)
+	if lang, ok := filenameLanguages[base]; ok {
+		return Detection{Language: lang, Confidence: ConfidenceFilename, Source: "filename"}
+	}
+
+	if lang := detectModeline(content); lang != "" {
+		return Detection{Language: lang, Confidence: ConfidenceModeline, Source: "modeline"}
+	}
+
+	if lang, ok := extensionLanguages[strings.ToLower(filepath.Ext(base))]; ok {
+		return Detection{Language: lang, Confidence: ConfidenceExtension, Source: "extension"}
+	}
+
+	if lang := detectShebang(content); lang != "" {
+		return Detection{Language: lang, Confidence: ConfidenceShebang, Source: "shebang"}
+	}
+
+	return Detection{Language: "plaintext", Confidence: ConfidenceUnknown, Source: "none"}
+}
+
+// readHead reads the first bytes of a file on disk, returning nil if it can't be read
+func readHead(path string) []byte {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	return readHeadFrom(file)
+}
+
+// readHeadFS reads the first bytes of a file in fsys, returning nil if it can't be read
+func readHeadFS(fsys fs.FS, name string) []byte {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	return readHeadFrom(file)
+}
+
+// readHeadFrom reads up to detectionHeadSize bytes
+func readHeadFrom(file io.Reader) []byte {
+	head := make([]byte, detectionHeadSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil
+	}
+	return head[:n]
+}
+
+// detectShebang maps a "#!" interpreter line to a language
+func detectShebang(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// "#!/usr/bin/env -S python3 -u" names the interpreter after env and its flags
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+
+	if lang, ok := interpreterLanguages[interpreter]; ok {
+		return lang
+	}
+
+	// Versioned interpreters such as python3.12 or ruby2.7
+	trimmed := strings.TrimRight(interpreter, "0123456789.")
+	return interpreterLanguages[trimmed]
+}
+
+// detectModeline looks for vim or emacs modelines in the first and last lines
+func detectModeline(content []byte) string {
+	if len(content) == 0 {
+		return ""
+	}
+
+	lines := strings.Split(string(content), "\n")
+	candidates := lines
+	if len(lines) > modelineLines*2 {
+		candidates = append(lines[:modelineLines:modelineLines], lines[len(lines)-modelineLines:]...)
+	}
+
+	for _, line := range candidates {
+		if match := vimModeline.FindStringSubmatch(line); match != nil {
+			return NormalizeLanguage(match[1])
+		}
+		if match := emacsModeline.FindStringSubmatch(line); match != nil {
+			return NormalizeLanguage(match[1])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,185 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    Detection
+	}{
+		{
+			name: "file name",
+			path: "build/Makefile",
+			want: Detection{Language: "makefile", Confidence: ConfidenceFilename, Source: "filename"},
+		},
+		{
+			name:    "file name beats modeline",
+			path:    "Dockerfile",
+			content: "# vim: set ft=bash:\nFROM scratch\n",
+			want:    Detection{Language: "dockerfile", Confidence: ConfidenceFilename, Source: "filename"},
+		},
+		{
+			name:    "vim modeline beats extension",
+			path:    "config.txt.in",
+			content: "# vim: set ft=python:\nx = 1\n",
+			want:    Detection{Language: "python", Confidence: ConfidenceModeline, Source: "modeline"},
+		},
+		{
+			name:    "emacs modeline with an alias",
+			path:    "script.h",
+			content: "/* -*- mode: c++; indent-tabs-mode: nil -*- */\n",
+			want:    Detection{Language: "cpp", Confidence: ConfidenceModeline, Source: "modeline"},
+		},
+		{
+			name: "extension",
+			path: "src/app.tsx",
+			want: Detection{Language: "typescript", Confidence: ConfidenceExtension, Source: "extension"},
+		},
+		{
+			name: "extension in upper case",
+			path: "LEGACY.C",
+			want: Detection{Language: "c", Confidence: ConfidenceExtension, Source: "extension"},
+		},
+		{
+			name:    "extension beats shebang",
+			path:    "run.rb",
+			content: "#!/bin/sh\n",
+			want:    Detection{Language: "ruby", Confidence: ConfidenceExtension, Source: "extension"},
+		},
+		{
+			name:    "shebang",
+			path:    "bin/tool",
+			content: "#!/usr/bin/env node\nconsole.log(1)\n",
+			want:    Detection{Language: "javascript", Confidence: ConfidenceShebang, Source: "shebang"},
+		},
+		{
+			name:    "unknown",
+			path:    "LICENSE",
+			content: "Permission is hereby granted\n",
+			want:    Detection{Language: "plaintext", Confidence: ConfidenceUnknown, Source: "none"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.path, []byte(tt.content)); got != tt.want {
+				t.Errorf("Detect(%q) = %+v, want %+v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectShebang(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"#!/bin/bash\n", "bash"},
+		{"#!/bin/sh -e\n", "bash"},
+		{"#!/usr/bin/python3\n", "python"},
+		{"#!/usr/bin/env python3\n", "python"},
+		{"#!/usr/bin/env -S python3 -u\n", "python"},
+		{"#!/usr/bin/env PYTHONPATH=. python\n", "python"},
+		{"#!/usr/local/bin/python3.12\n", "python"},
+		{"#!/usr/bin/ruby2.7\n", "ruby"},
+		{"#! /usr/bin/perl -w\n", "perl"},
+		{"#!/usr/bin/env\n", ""},
+		{"#!/usr/bin/awk -f\n", ""},
+		{"#!\n", ""},
+		{"# comment\n", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := detectShebang([]byte(tt.content)); got != tt.want {
+			t.Errorf("detectShebang(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestDetectModeline(t *testing.T) {
+	// Modelines are looked for in the first and last lines only
+	middle := strings.Repeat("x\n", 2*modelineLines)
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"vim set", "# vim: set ft=ruby:\n", "ruby"},
+		{"vim filetype", "// vi: filetype=javascript\n", "javascript"},
+		{"vim syntax alias", "# vim: syntax=sh\n", "bash"},
+		{"emacs mode", "# -*- mode: python -*-\n", "python"},
+		{"emacs bare", "# -*- perl -*-\n", "perl"},
+		{"emacs with other variables", "/* -*- coding: utf-8; mode: go -*- */\n", "go"},
+		{"last lines", "x\n" + middle + "# vim: ft=lua\n", "lua"},
+		{"middle ignored", "x\n" + middle[:len(middle)/2] + "# vim: ft=lua\n" + middle, ""},
+		{"none", "package main\n", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectModeline([]byte(tt.content)); got != tt.want {
+				t.Errorf("detectModeline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadLanguageConfig(t *testing.T) {
+	saved := func(m map[string]string, key string) {
+		old, ok := m[key]
+		t.Cleanup(func() {
+			if ok {
+				m[key] = old
+			} else {
+				delete(m, key)
+			}
+		})
+	}
+	saved(filenameLanguages, "Tiltfile")
+	saved(extensionLanguages, ".tpl")
+	saved(interpreterLanguages, "bun")
+
+	file := filepath.Join(t.TempDir(), "languages.json")
+	config := `{"filenames": {"Tiltfile": "py"}, "extensions": {"TPL": "HTML"}, "interpreters": {"bun": "ts"}}`
+	if err := os.WriteFile(file, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadLanguageConfig(file); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{"Tiltfile", "", "python"},
+		{"page.tpl", "", "html"},
+		{"serve", "#!/usr/bin/env bun\n", "typescript"},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguageContent(tt.path, []byte(tt.content)); got != tt.want {
+			t.Errorf("DetectLanguageContent(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if err := LoadLanguageConfig(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("LoadLanguageConfig() of a missing file = %v, want nil", err)
+	}
+	if err := os.WriteFile(file, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadLanguageConfig(file); err == nil {
+		t.Error("LoadLanguageConfig() of invalid JSON succeeded")
+	}
+}
@@ -0,0 +1,327 @@
+package finder
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// SourceKind classifies a run of source text
+type SourceKind int
+
+const (
+	KindCode SourceKind = iota
+	KindComment
+	KindString
+)
+
+// Region is a run of text of one kind within a line, as byte offsets
+type Region struct {
+	Start int
+	End   int
+	Kind  SourceKind
+}
+
+// lexSyntax describes the comment and string syntax of a language
+type lexSyntax struct {
+	lineComments  []string
+	blockComments [][2]string
+	strings       []stringSyntax // Longer openers such as """ must come first
+	charLiterals  bool           // ' only opens 'x' or '\x' char literals, as lifetimes also use it
+	commentSpace  bool           // Line comments must start the line or follow whitespace
+}
+
+// stringSyntax describes one kind of string literal
+type stringSyntax struct {
+	open, close string
+	escapes     bool // A backslash escapes the next character
+	multiline   bool // The literal may continue onto following lines
+}
+
+var (
+	doubleQuoted = stringSyntax{open: `"`, close: `"`, escapes: true}
+	singleQuoted = stringSyntax{open: `'`, close: `'`, escapes: true}
+	cStrings     = []stringSyntax{doubleQuoted, singleQuoted}
+	cComments    = [][2]string{{"/*", "*/"}}
+	htmlComments = [][2]string{{"<!--", "-->"}}
+)
+
+// lexSyntaxes maps languages to their comment and string syntax
+var lexSyntaxes = map[string]lexSyntax{
+	"go": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings:       []stringSyntax{doubleQuoted, singleQuoted, {open: "`", close: "`", multiline: true}},
+	},
+	"javascript": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings:       []stringSyntax{doubleQuoted, singleQuoted, {open: "`", close: "`", escapes: true, multiline: true}},
+	},
+	"typescript": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings:       []stringSyntax{doubleQuoted, singleQuoted, {open: "`", close: "`", escapes: true, multiline: true}},
+	},
+	"java": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings:       []stringSyntax{{open: `"""`, close: `"""`, escapes: true, multiline: true}, doubleQuoted, singleQuoted},
+	},
+	"kotlin": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings:       []stringSyntax{{open: `"""`, close: `"""`, multiline: true}, doubleQuoted, singleQuoted},
+	},
+	"scala": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings:       []stringSyntax{{open: `"""`, close: `"""`, multiline: true}, doubleQuoted, singleQuoted},
+	},
+	"c":      {lineComments: []string{"//"}, blockComments: cComments, strings: cStrings},
+	"cpp":    {lineComments: []string{"//"}, blockComments: cComments, strings: cStrings},
+	"csharp": {lineComments: []string{"//"}, blockComments: cComments, strings: cStrings},
+	"swift":  {lineComments: []string{"//"}, blockComments: cComments, strings: cStrings},
+	"rust": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		strings:       []stringSyntax{{open: `"`, close: `"`, escapes: true, multiline: true}},
+		charLiterals:  true,
+	},
+	"php": {
+		lineComments:  []string{"//", "#"},
+		blockComments: cComments,
+		strings:       cStrings,
+	},
+	"python": {
+		lineComments: []string{"#"},
+		strings: []stringSyntax{
+			{open: `"""`, close: `"""`, escapes: true, multiline: true},
+			{open: `'''`, close: `'''`, escapes: true, multiline: true},
+			doubleQuoted, singleQuoted,
+		},
+	},
+	"ruby": {
+		lineComments: []string{"#"},
+		strings:      []stringSyntax{doubleQuoted, singleQuoted, {open: "`", close: "`", escapes: true}},
+	},
+	"bash": {
+		lineComments: []string{"#"},
+		strings:      []stringSyntax{{open: `"`, close: `"`, escapes: true, multiline: true}, {open: `'`, close: `'`, multiline: true}},
+		commentSpace: true,
+	},
+	"perl":       {lineComments: []string{"#"}, strings: cStrings, commentSpace: true},
+	"makefile":   {lineComments: []string{"#"}, commentSpace: true},
+	"dockerfile": {lineComments: []string{"#"}, strings: cStrings, commentSpace: true},
+	"yaml":       {lineComments: []string{"#"}, strings: []stringSyntax{doubleQuoted, {open: `'`, close: `'`}}, commentSpace: true},
+	"toml":       {lineComments: []string{"#"}, strings: []stringSyntax{doubleQuoted, {open: `'`, close: `'`}}, commentSpace: true},
+	"lua": {
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"--[[", "]]"}},
+		strings:       cStrings,
+	},
+	"sql": {
+		lineComments:  []string{"--"},
+		blockComments: cComments,
+		strings:       []stringSyntax{{open: `'`, close: `'`}, {open: `"`, close: `"`}},
+	},
+	"css":      {blockComments: cComments, strings: cStrings},
+	"scss":     {lineComments: []string{"//"}, blockComments: cComments, strings: cStrings},
+	"html":     {blockComments: htmlComments},
+	"xml":      {blockComments: htmlComments},
+	"markdown": {blockComments: htmlComments},
+	"json":     {strings: []stringSyntax{doubleQuoted}},
+}
+
+// lexState is what carries over from one line to the next: an unterminated
+// block comment or multi-line string
+type lexState struct {
+	kind    SourceKind // KindCode when nothing is open
+	close   string
+	escapes bool
+}
+
+// LexLines splits each line into code, comment and string regions. Block
+// comments and multi-line strings carry across lines. Languages without
+// known syntax are treated as code throughout.
+func LexLines(language string, lines []string) [][]Region {
+	regions := make([][]Region, len(lines))
+	syntax, known := lexSyntaxes[language]
+
+	var state lexState
+	for i, line := range lines {
+		if !known {
+			if line != "" {
+				regions[i] = []Region{{Start: 0, End: len(line), Kind: KindCode}}
+			}
+			continue
+		}
+		regions[i] = syntax.lexLine(line, &state)
+	}
+
+	return regions
+}
+
+// MaskCode returns lines with every comment and string replaced by spaces,
+// keeping byte offsets, so structure can be scanned without being misled by
+// braces or keywords inside them
+func MaskCode(language string, lines []string) []string {
+	if _, known := lexSyntaxes[language]; !known {
+		return lines
+	}
+	return MaskExcept(language, lines, KindCode)
+}
+
+// MaskExcept returns lines with all text outside the given kinds replaced by
+// spaces, keeping byte offsets, so that only those kinds can be matched
+func MaskExcept(language string, lines []string, keep ...SourceKind) []string {
+	masked := make([]string, len(lines))
+	for i, regions := range LexLines(language, lines) {
+		line := []byte(lines[i])
+		for _, region := range regions {
+			if slices.Contains(keep, region.Kind) {
+				continue
+			}
+			for j := region.Start; j < region.End; j++ {
+				line[j] = ' '
+			}
+		}
+		masked[i] = string(line)
+	}
+	return masked
+}
+
+// lexLine classifies one line, updating the state carried to the next
+func (s lexSyntax) lexLine(line string, state *lexState) []Region {
+	var regions []Region
+	start := 0
+	emit := func(end int, kind SourceKind) {
+		end = min(end, len(line))
+		if end > start {
+			regions = append(regions, Region{Start: start, End: end, Kind: kind})
+		}
+		start = end
+	}
+
+	for pos := 0; pos < len(line); {
+		// Inside a comment or string, look for its end
+		if state.kind != KindCode {
+			if state.escapes && line[pos] == '\\' {
+				pos += 2
+				continue
+			}
+			if strings.HasPrefix(line[pos:], state.close) {
+				pos += len(state.close)
+				emit(pos, state.kind)
+				*state = lexState{}
+				continue
+			}
+			pos++
+			continue
+		}
+
+		rest := line[pos:]
+
+		if closer, n := s.blockCommentAt(rest); n > 0 {
+			emit(pos, KindCode)
+			*state = lexState{kind: KindComment, close: closer}
+			pos += n
+			continue
+		}
+
+		if s.lineCommentAt(line, pos) {
+			emit(pos, KindCode)
+			emit(len(line), KindComment)
+			return regions
+		}
+
+		if str, ok := s.stringAt(rest); ok {
+			emit(pos, KindCode)
+			*state = lexState{kind: KindString, close: str.close, escapes: str.escapes}
+			pos += len(str.open)
+			continue
+		}
+
+		if s.charLiterals && line[pos] == '\'' {
+			if n := charLiteralLength(rest); n > 0 {
+				emit(pos, KindCode)
+				pos += n
+				emit(pos, KindString)
+				continue
+			}
+		}
+
+		pos++
+	}
+
+	emit(len(line), state.kind)
+
+	// Strings that can't span lines end with the line, even if unterminated
+	if state.kind == KindString && !s.multilineString(state.close) {
+		*state = lexState{}
+	}
+	return regions
+}
+
+// blockCommentAt returns the closer and opener length of a block comment starting at text
+func (s lexSyntax) blockCommentAt(text string) (string, int) {
+	for _, comment := range s.blockComments {
+		if strings.HasPrefix(text, comment[0]) {
+			return comment[1], len(comment[0])
+		}
+	}
+	return "", 0
+}
+
+// lineCommentAt reports whether a line comment starts at pos
+func (s lexSyntax) lineCommentAt(line string, pos int) bool {
+	for _, marker := range s.lineComments {
+		if !strings.HasPrefix(line[pos:], marker) {
+			continue
+		}
+		if s.commentSpace && pos > 0 && line[pos-1] != ' ' && line[pos-1] != '\t' {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// stringAt returns the string syntax whose opener starts text
+func (s lexSyntax) stringAt(text string) (stringSyntax, bool) {
+	for _, str := range s.strings {
+		if strings.HasPrefix(text, str.open) {
+			return str, true
+		}
+	}
+	return stringSyntax{}, false
+}
+
+// multilineString reports whether strings closed by close may span lines
+func (s lexSyntax) multilineString(close string) bool {
+	for _, str := range s.strings {
+		if str.close == close && str.multiline {
+			return true
+		}
+	}
+	return false
+}
+
+// charLiteralLength returns the length of a char literal such as 'x' or
+// '\n' at the start of text, or 0 when the quote is something else, such
+// as a Rust lifetime
+func charLiteralLength(text string) int {
+	if len(text) >= 3 && text[1] == '\\' {
+		// Escapes run from 'n' up to '\u{10FFFF}'
+		if end := strings.IndexByte(text[2:], '\''); end >= 0 && end <= 10 {
+			return end + 3
+		}
+		return 0
+	}
+
+	_, size := utf8.DecodeRuneInString(text[1:])
+	if len(text) > 1+size && text[1+size] == '\'' {
+		return size + 2
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package finder
+
+import (
+	"reflect"
+	"testing"
+)
+
+// kinds renders the regions of a line one byte per character: c for code,
+// # for comments and s for strings
+func kinds(line string, regions []Region) string {
+	out := []byte(line)
+	for i := range out {
+		out[i] = '.'
+	}
+	for _, region := range regions {
+		mark := map[SourceKind]byte{KindCode: 'c', KindComment: '#', KindString: 's'}[region.Kind]
+		for i := region.Start; i < region.End; i++ {
+			out[i] = mark
+		}
+	}
+	return string(out)
+}
+
+func TestLexLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		lines    []string
+		want     []string
+	}{
+		{"braces in strings and comments", "go", []string{`s := "a{b}" // }`}, []string{"cccccssssssc####"}},
+		{"raw string across lines", "go", []string{"x := `a", "b} `+y"}, []string{"cccccss", "sssscc"}},
+		{"escaped quote", "go", []string{`"a\"b" + c`}, []string{"sssssscccc"}},
+		{"unterminated string ends with the line", "go", []string{`"abc`, `d`}, []string{"ssss", "c"}},
+		{"block comment across lines", "c", []string{"a /* b", "c */ d"}, []string{"cc####", "####cc"}},
+		{
+			"python docstring and hash in a string", "python",
+			[]string{`x = """doc`, `} still"""`, `y = '#' # c`},
+			[]string{"ccccssssss", "ssssssssss", "ccccsssc###"},
+		},
+		{"rust lifetimes aren't strings", "rust", []string{`fn f<'a>(x: &'a str) -> char { '\n' }`}, []string{"cccccccccccccccccccccccccccccccsssscc"}},
+		{"shell comment needs a space", "bash", []string{`echo ${#x} # c`}, []string{"ccccccccccc###"}},
+		{"lua block comment", "lua", []string{`a --[[ b`, `]] c -- d`}, []string{"cc######", "##ccc####"}},
+		{"html comment", "html", []string{`<p><!-- x --></p>`}, []string{"ccc##########cccc"}},
+		{"unknown language is code", "unknown", []string{`a // b "c"`, ``}, []string{"cccccccccc", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regions := LexLines(tt.language, tt.lines)
+			var got []string
+			for i, line := range tt.lines {
+				got = append(got, kinds(line, regions[i]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LexLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskCode(t *testing.T) {
+	lines := []string{`f("}") /* { */`, `g() // {`}
+	want := []string{`f(   )        `, `g()     `}
+	if got := MaskCode("go", lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("MaskCode() = %q, want %q", got, want)
+	}
+
+	// Keeping several kinds only blanks the rest
+	if got := MaskExcept("go", lines, KindCode, KindString); !reflect.DeepEqual(got, []string{`f("}")        `, `g()     `}) {
+		t.Errorf("MaskExcept(code, string) = %q", got)
+	}
+	if got := MaskExcept("go", lines, KindComment); !reflect.DeepEqual(got, []string{`       /* { */`, `    // {`}) {
+		t.Errorf("MaskExcept(comment) = %q", got)
+	}
+
+	// Without known syntax nothing is masked
+	if got := MaskCode("unknown", lines); !reflect.DeepEqual(got, lines) {
+		t.Errorf("MaskCode(unknown) = %q, want the lines unchanged", got)
+	}
+}
@@ -0,0 +1,174 @@
+package finder
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is a read-only, in-memory file system. File content can be given up
+// front or loaded lazily on first open, which lets archives and git trees be
+// listed without reading every file. Parent directories are implied.
+type MemFS struct {
+	files map[string]*memEntry
+	dirs  map[string][]string // directory name to the names of its direct children
+}
+
+// memEntry is a single file in a MemFS
+type memEntry struct {
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	load    func() ([]byte, error)
+
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// NewMemFS creates an empty in-memory file system
+func NewMemFS() *MemFS {
+	return &MemFS{
+		files: make(map[string]*memEntry),
+		dirs:  map[string][]string{".": nil},
+	}
+}
+
+// AddFile adds a file with known content
+func (m *MemFS) AddFile(name string, data []byte, mode fs.FileMode, modTime time.Time) {
+	m.AddLazyFile(name, int64(len(data)), mode, modTime, func() ([]byte, error) { return data, nil })
+}
+
+// AddLazyFile adds a file whose content is produced by load when first read
+func (m *MemFS) AddLazyFile(name string, size int64, mode fs.FileMode, modTime time.Time, load func() ([]byte, error)) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if !fs.ValidPath(name) || name == "." {
+		return
+	}
+
+	if _, exists := m.files[name]; !exists {
+		m.addChild(name)
+	}
+	m.files[name] = &memEntry{size: size, mode: mode.Perm(), modTime: modTime, load: load}
+}
+
+// addChild records name in its parent directory, creating parents as needed
+func (m *MemFS) addChild(name string) {
+	parent := path.Dir(name)
+	if _, exists := m.dirs[parent]; !exists {
+		m.addChild(parent)
+		m.dirs[parent] = nil
+	}
+	m.dirs[parent] = append(m.dirs[parent], name)
+}
+
+// Open implements fs.FS
+func (m *MemFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if entry, ok := m.files[name]; ok {
+		entry.once.Do(func() {
+			entry.data, entry.err = entry.load()
+		})
+		if entry.err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: entry.err}
+		}
+		return &memFile{
+			info:   memInfo{name: path.Base(name), size: int64(len(entry.data)), mode: entry.mode, modTime: entry.modTime},
+			reader: bytes.NewReader(entry.data),
+		}, nil
+	}
+
+	if children, ok := m.dirs[name]; ok {
+		entries := make([]fs.DirEntry, 0, len(children))
+		for _, child := range children {
+			entries = append(entries, fs.FileInfoToDirEntry(m.info(child)))
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+		return &memDir{info: m.info(name), entries: entries}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat implements fs.StatFS without loading file content
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	if _, isFile := m.files[name]; isFile {
+		return m.info(name), nil
+	}
+	if _, isDir := m.dirs[name]; isDir {
+		return m.info(name), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// info describes a file or directory
+func (m *MemFS) info(name string) memInfo {
+	if entry, ok := m.files[name]; ok {
+		return memInfo{name: path.Base(name), size: entry.size, mode: entry.mode, modTime: entry.modTime}
+	}
+	return memInfo{name: path.Base(name), mode: fs.ModeDir | 0o755}
+}
+
+// memInfo implements fs.FileInfo
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return i.mode }
+func (i memInfo) ModTime() time.Time { return i.modTime }
+func (i memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+// memFile is an open regular file
+type memFile struct {
+	info   memInfo
+	reader *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Read(b []byte) (int, error) { return f.reader.Read(b) }
+func (f *memFile) Close() error               { return nil }
+
+// memDir is an open directory
+type memDir struct {
+	info    memInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read(b []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(remaining))
+	d.offset += n
+	return remaining[:n], nil
+}
@@ -0,0 +1,212 @@
+package finder
+
+import (
+	"context"
+	"io/fs"
+	"runtime"
+	"sync"
+)
+
+// streamBuffer is the number of paths the walker may queue ahead of the workers
+const streamBuffer = 256
+
+// Limits bounds the resources used when processing files concurrently
+type Limits struct {
+	Jobs      int   // Number of workers (0 for one per CPU)
+	MaxMemory int64 // Bytes of files being worked on or waiting for their turn in the output (0 for no limit)
+}
+
+// workers returns the effective number of workers
+func (l Limits) workers() int {
+	if l.Jobs > 0 {
+		return l.Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// memoryBudget is a weighted semaphore over the bytes of file content in flight
+type memoryBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int64
+	used  int64
+}
+
+// newMemoryBudget creates a budget; a zero limit never blocks
+func newMemoryBudget(limit int64) *memoryBudget {
+	b := &memoryBudget{limit: limit}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire reserves n bytes, waiting until they fit. A file larger than the
+// whole budget is let through once nothing else is in flight, and the
+// returned amount is what must be released.
+func (b *memoryBudget) acquire(n int64) int64 {
+	if b.limit <= 0 {
+		return 0
+	}
+	n = min(n, b.limit)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.used > 0 && b.used+n > b.limit {
+		b.cond.Wait()
+	}
+	b.used += n
+	return n
+}
+
+// release returns n bytes to the budget
+func (b *memoryBudget) release(n int64) {
+	if b.limit <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// sequenced is a path numbered by its position in the input stream, with
+// what the pipeline learned of it before the work ran
+type sequenced struct {
+	index    int
+	path     string
+	info     fs.FileInfo // Nil when the file couldn't be stat'ed
+	reserved int64       // Bytes held against the memory budget until the result is emitted
+}
+
+// outcome is the result of the work on one path
+type outcome[T any] struct {
+	sequenced
+	result T
+	err    error
+}
+
+// Pipeline runs work on every path received from paths using a bounded pool
+// of workers, and returns the results in the order the paths arrived so the
+// output is the same from run to run. It collects what PipelineEach emits.
+func Pipeline[T any](ctx context.Context, src Source, paths <-chan string, limits Limits, work func(path string, info fs.FileInfo) (T, error)) ([]T, error) {
+	var results []T
+	err := PipelineEach(ctx, src, paths, limits, work, func(result T) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// PipelineEach runs work on every path received from paths using a bounded
+// pool of workers, and passes each result to emit in the order the paths
+// arrived, as soon as the results before it are in. Each file is stat'ed
+// once and its info handed to work; its size is reserved against
+// limits.MaxMemory from before the work starts until its result has been
+// emitted, so results waiting on slower files count against the budget too.
+// The first error, from work or emit, stops the pipeline.
+func PipelineEach[T any](ctx context.Context, src Source, paths <-chan string, limits Limits, work func(path string, info fs.FileInfo) (T, error), emit func(T) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan sequenced)
+	done := make(chan outcome[T])
+	budget := newMemoryBudget(limits.MaxMemory)
+
+	var wg sync.WaitGroup
+	for i := 0; i < limits.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				result, err := work(job.path, job.info)
+				done <- outcome[T]{sequenced: job, result: result, err: err}
+			}
+		}()
+	}
+
+	// Number the paths as they arrive and reserve their size in that order,
+	// so the budget is always held by the earliest results yet to be emitted
+	// and the next one to emit can't be kept waiting for it. The feed stops
+	// early once an error cancels the run.
+	go func() {
+		defer close(jobs)
+		count := 0
+		for path := range paths {
+			if ctx.Err() != nil {
+				return
+			}
+			job := sequenced{index: count, path: path}
+			if info, err := src.Stat(path); err == nil {
+				job.info = info
+				job.reserved = budget.acquire(info.Size())
+			}
+			select {
+			case jobs <- job:
+				count++
+			case <-ctx.Done():
+				budget.release(job.reserved)
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Emit in order, holding back results that arrive ahead of their turn.
+	// After an error the rest are drained without being emitted.
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	pending := make(map[int]outcome[T])
+	next := 0
+	for out := range done {
+		if out.err != nil {
+			fail(out.err)
+		}
+		pending[out.index] = out
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if firstErr == nil {
+				if err := emit(ready.result); err != nil {
+					fail(err)
+				}
+			}
+			budget.release(ready.reserved)
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// SendPaths streams a fixed list of paths over a channel
+func SendPaths(ctx context.Context, paths []string) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for _, path := range paths {
+			select {
+			case out <- path:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,199 @@
+package finder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// countingFS counts the Stat calls made on a file system
+type countingFS struct {
+	fstest.MapFS
+	stats atomic.Int64
+}
+
+func (c *countingFS) Stat(name string) (fs.FileInfo, error) {
+	c.stats.Add(1)
+	return c.MapFS.Stat(name)
+}
+
+// pipelineTree returns n files named f00, f01, ... of size bytes each
+func pipelineTree(n, size int) (fstest.MapFS, []string) {
+	fsys := make(fstest.MapFS)
+	var paths []string
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("f%02d", i)
+		fsys[name] = &fstest.MapFile{Data: []byte(strings.Repeat("x", size))}
+		paths = append(paths, name)
+	}
+	return fsys, paths
+}
+
+func TestPipelineOrder(t *testing.T) {
+	fsys, paths := pipelineTree(40, 4)
+	counting := &countingFS{MapFS: fsys}
+	src := FSSource(counting, "")
+
+	// Earlier files take longer, so they finish last
+	got, err := Pipeline(context.Background(), src, SendPaths(context.Background(), paths), Limits{Jobs: 8},
+		func(path string, info fs.FileInfo) (string, error) {
+			var i int
+			fmt.Sscanf(path, "f%d", &i)
+			time.Sleep(time.Duration(len(paths)-i) * 100 * time.Microsecond)
+			if info == nil || info.Size() != 4 {
+				return "", fmt.Errorf("%s: no file info passed to work", path)
+			}
+			return path, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, paths) {
+		t.Errorf("Pipeline() = %q, want input order %q", got, paths)
+	}
+	if n := counting.stats.Load(); n != int64(len(paths)) {
+		t.Errorf("files stat'ed %d times, want once each (%d)", n, len(paths))
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	fsys, paths := pipelineTree(40, 4)
+	src := FSSource(fsys, "")
+	failure := errors.New("unreadable")
+
+	var emitted []string
+	err := PipelineEach(context.Background(), src, SendPaths(context.Background(), paths), Limits{Jobs: 4},
+		func(path string, _ fs.FileInfo) (string, error) {
+			if path == "f05" {
+				return "", failure
+			}
+			return path, nil
+		},
+		func(path string) error {
+			emitted = append(emitted, path)
+			return nil
+		})
+	if !errors.Is(err, failure) {
+		t.Fatalf("PipelineEach() error = %v, want %v", err, failure)
+	}
+	for _, path := range emitted {
+		if path >= "f05" {
+			t.Errorf("emitted %s after the failing file", path)
+		}
+	}
+}
+
+func TestPipelineCancel(t *testing.T) {
+	fsys, paths := pipelineTree(1, 4)
+	src := FSSource(fsys, "")
+
+	// An endless stream of paths, stopped by cancelling the context
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := make(chan string)
+	go func() {
+		defer close(stream)
+		for {
+			select {
+			case stream <- paths[0]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	finished := make(chan error)
+	go func() {
+		finished <- PipelineEach(ctx, src, stream, Limits{Jobs: 4},
+			func(path string, _ fs.FileInfo) (string, error) { return path, nil },
+			func(string) error {
+				once.Do(cancel)
+				return nil
+			})
+	}()
+
+	select {
+	case err := <-finished:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("PipelineEach() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("PipelineEach() didn't stop after the context was cancelled")
+	}
+}
+
+func TestPipelineMemoryBudget(t *testing.T) {
+	const size, limit = 4, 10
+
+	fsys, paths := pipelineTree(30, size)
+	fsys["big"] = &fstest.MapFile{Data: []byte(strings.Repeat("x", 3*limit))}
+	paths = append(paths[:10], append([]string{"big"}, paths[10:]...)...)
+	src := FSSource(fsys, "")
+
+	// Bytes of files being worked on or waiting to be emitted
+	var mu sync.Mutex
+	var held, peak, bigWith int64
+	err := PipelineEach(context.Background(), src, SendPaths(context.Background(), paths), Limits{Jobs: 8, MaxMemory: limit},
+		func(path string, info fs.FileInfo) (int64, error) {
+			mu.Lock()
+			held += info.Size()
+			peak = max(peak, held)
+			if path == "big" {
+				bigWith = held - info.Size()
+			}
+			mu.Unlock()
+
+			// Later files finish first and must wait for the earlier ones
+			var i int
+			fmt.Sscanf(path, "f%d", &i)
+			time.Sleep(time.Duration(30-i) * 50 * time.Microsecond)
+			return info.Size(), nil
+		},
+		func(n int64) error {
+			mu.Lock()
+			held -= n
+			mu.Unlock()
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak > 3*limit {
+		t.Errorf("peak of %d bytes held, want the oversized file alone (%d)", peak, 3*limit)
+	}
+	if bigWith != 0 {
+		t.Errorf("oversized file ran alongside %d other bytes", bigWith)
+	}
+
+	// Without the oversized file, the budget holds throughout
+	peak = 0
+	err = PipelineEach(context.Background(), src, SendPaths(context.Background(), paths[:10]), Limits{Jobs: 8, MaxMemory: limit},
+		func(_ string, info fs.FileInfo) (int64, error) {
+			mu.Lock()
+			held += info.Size()
+			peak = max(peak, held)
+			mu.Unlock()
+			time.Sleep(100 * time.Microsecond)
+			return info.Size(), nil
+		},
+		func(n int64) error {
+			mu.Lock()
+			held -= n
+			mu.Unlock()
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak > limit {
+		t.Errorf("peak of %d bytes held, want at most %d", peak, limit)
+	}
+}
@@ -0,0 +1,398 @@
+package finder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// codeFilesPattern selects the files treated as code when no include pattern
+// is given: those with an extension known to language detection, including
+// any registered from a language config
+func codeFilesPattern() string {
+	var exts []string
+	for ext := range extensionLanguages {
+		exts = append(exts, strings.TrimPrefix(ext, "."))
+	}
+	sort.Strings(exts)
+	return "**/*.{" + strings.Join(exts, ",") + "}"
+}
+
+// Options controls how files are located
+type Options struct {
+	NoIgnore  bool     // Don't apply .gitignore, .codeclipignore or .git/info/exclude rules
+	Include   []string // Patterns selecting files; a pattern without "/" matches at any depth
+	Exclude   []string // Patterns removing files and pruning directories; same matching as Include
+	Languages []string // Only keep files whose detected language is in this list
+	Only      []string // When non-nil, select from these names within the source instead of walking
+
+	// FollowSymlinks descends into symlinked directories of sources on disk.
+	// Symlinked files are selected either way, but with it a file found under
+	// several names is selected once, and its other names are recorded in
+	// Aliases when that is set.
+	FollowSymlinks bool
+	Aliases        *Aliases
+
+	MaxFileSize int64        // Skip files larger than this many bytes when reading (0 for no limit)
+	Allow       []SkipReason // Skip categories to read anyway
+	Limits      Limits       // Concurrency and memory bounds when reading
+
+	// Warnings collects files that couldn't be walked or read so the run can
+	// continue without them. When nil the first such error is returned.
+	Warnings *Warnings
+}
+
+// languageAliases maps common short names to the names returned by DetectLanguage
+var languageAliases = map[string]string{
+	"js":     "javascript",
+	"ts":     "typescript",
+	"py":     "python",
+	"rb":     "ruby",
+	"sh":     "bash",
+	"shell":  "bash",
+	"c++":    "cpp",
+	"cs":     "csharp",
+	"c#":     "csharp",
+	"yml":    "yaml",
+	"md":     "markdown",
+	"text":   "plaintext",
+	"txt":    "plaintext",
+	"make":   "makefile",
+	"cperl":  "perl",
+	"golang": "go",
+
+	"shell-script":    "bash",
+	"javascriptreact": "javascript",
+	"typescriptreact": "typescript",
+}
+
+// selector is the compiled form of the selection options
+type selector struct {
+	include   []string
+	exclude   []string
+	languages map[string]bool
+}
+
+// newSelector validates the patterns and builds a selector. globs are used
+// exactly as given, while opts.Include and opts.Exclude patterns without a
+// slash are expanded to match at any depth.
+func newSelector(globs []string, opts Options) (*selector, error) {
+	sel := &selector{}
+
+	for _, glob := range globs {
+		if !doublestar.ValidatePattern(glob) {
+			return nil, fmt.Errorf("invalid glob pattern: %s", glob)
+		}
+		sel.include = append(sel.include, glob)
+	}
+	for _, pattern := range opts.Include {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid include pattern: %s", pattern)
+		}
+		sel.include = append(sel.include, anyDepth(pattern))
+	}
+	for _, pattern := range opts.Exclude {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid exclude pattern: %s", pattern)
+		}
+		sel.exclude = append(sel.exclude, anyDepth(pattern))
+	}
+
+	if len(opts.Languages) > 0 {
+		sel.languages = make(map[string]bool)
+		for _, lang := range opts.Languages {
+			sel.languages[NormalizeLanguage(lang)] = true
+		}
+	}
+
+	// Without explicit includes pick every code file, or every file when
+	// filtering by language or from an explicit list so that those decide
+	if len(sel.include) == 0 {
+		if sel.languages != nil || opts.Only != nil {
+			sel.include = []string{"**"}
+		} else {
+			sel.include = []string{codeFilesPattern()}
+		}
+	}
+
+	return sel, nil
+}
+
+// excluded reports whether a slash-separated relative path matches an exclude pattern
+func (s *selector) excluded(relPath string) bool {
+	for _, pattern := range s.exclude {
+		if match, _ := doublestar.Match(pattern, relPath); match {
+			return true
+		}
+	}
+	return false
+}
+
+// selected reports whether a file passes the include and language selectors.
+// fsys is used to read the file when its name alone doesn't reveal the
+// language; it may be nil to detect from the name only.
+func (s *selector) selected(fsys fs.FS, relPath string) bool {
+	included := false
+	for _, pattern := range s.include {
+		if match, _ := doublestar.Match(pattern, relPath); match {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	if s.languages != nil {
+		var head []byte
+		if fsys != nil {
+			head = readHeadFS(fsys, relPath)
+		}
+		if !s.languages[Detect(relPath, head).Language] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SelectFiles walks src and returns the display paths of the files picked by
+// globs together with the include, exclude and language selectors in opts.
+// When neither globs nor opts.Include are given, all code files are candidates.
+func SelectFiles(src Source, globs []string, opts Options) ([]string, error) {
+	matches := []string{}
+	err := walkSelected(context.Background(), src, globs, opts, func(path string) error {
+		matches = append(matches, path)
+		return nil
+	})
+	return matches, err
+}
+
+// StreamFiles is the streaming form of SelectFiles. The walk runs in the
+// background and sends selected files in lexical order as they are found;
+// once the path channel is closed the error channel yields the walk result.
+// Cancelling ctx stops the walk.
+func StreamFiles(ctx context.Context, src Source, globs []string, opts Options) (<-chan string, <-chan error) {
+	paths := make(chan string, streamBuffer)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(paths)
+		errc <- walkSelected(ctx, src, globs, opts, func(path string) error {
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	return paths, errc
+}
+
+// walkSelected calls emit with the display path of every selected file in src
+func walkSelected(ctx context.Context, src Source, globs []string, opts Options, emit func(path string) error) error {
+	sel, err := newSelector(globs, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Only != nil {
+		return selectFromList(src, opts.Only, sel, opts, emit)
+	}
+
+	var ignore *IgnoreMatcher
+	if !opts.NoIgnore {
+		ignore = NewIgnoreMatcher(src)
+	}
+
+	walk := func(fn fs.WalkDirFunc) error {
+		return fs.WalkDir(src.FS, ".", fn)
+	}
+	var links *linkTracker
+	if opts.FollowSymlinks && src.Dir != "" {
+		walk = func(fn fs.WalkDirFunc) error {
+			return walkFollow(src, fn)
+		}
+		links = newLinkTracker(src, opts.Aliases)
+	}
+
+	return walk(func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// The source itself must be readable; anything below it can be skipped
+			if name == "." {
+				return err
+			}
+			return opts.Warnings.Record(src.Path(name), "walk", err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if name != "." {
+			// Prune ignored and excluded directories so their contents are never visited
+			if (entry.IsDir() && isGitDir(name)) || (ignore != nil && ignore.Match(name, entry.IsDir())) || sel.excluded(name) {
+				if entry.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if entry.IsDir() {
+			if ignore != nil {
+				ignore.LoadDir(src.FS, name)
+			}
+			return nil
+		}
+
+		if !entry.Type().IsRegular() && !linksToFile(src, name, entry) {
+			return nil
+		}
+
+		if !sel.selected(src.FS, name) {
+			return nil
+		}
+		if links != nil {
+			canonical, duplicate := links.visit(name)
+			if duplicate {
+				return nil
+			}
+			name = canonical
+		}
+		return emit(src.Path(name))
+	})
+}
+
+// linksToFile reports whether an entry is a symlink to be selected as a file.
+// The link is stat'ed through, and only links to directories are left out:
+// a broken link is kept so that reading it reports the failure.
+func linksToFile(src Source, name string, entry fs.DirEntry) bool {
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	info, err := fs.Stat(src.FS, name)
+	return err != nil || !info.IsDir()
+}
+
+// PathMatcher applies the include, exclude and language selectors to
+// relative paths without touching the file system
+type PathMatcher struct {
+	sel *selector
+}
+
+// NewPathMatcher builds a matcher for explicit path lists. Ignore rules and
+// opts.Only are not used, and without include patterns every path is a candidate.
+func NewPathMatcher(opts Options) (*PathMatcher, error) {
+	if opts.Only == nil {
+		opts.Only = []string{}
+	}
+	sel, err := newSelector(nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PathMatcher{sel: sel}, nil
+}
+
+// Match reports whether a slash-separated relative path passes the selectors
+func (m *PathMatcher) Match(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	return !m.sel.excludedWithParents(relPath) && m.sel.selected(nil, relPath)
+}
+
+// selectFromList applies the selectors to an explicit list of names in src,
+// such as the files reported by git, without walking the tree
+func selectFromList(src Source, names []string, sel *selector, opts Options, emit func(path string) error) error {
+	var ignore *IgnoreMatcher
+	if !opts.NoIgnore {
+		ignore = NewIgnoreMatcher(src)
+	}
+
+	for _, name := range names {
+		name = filepath.ToSlash(name)
+
+		info, err := fs.Stat(src.FS, name)
+		if err != nil {
+			// Listed files that no longer exist are expected; anything else is reported
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err := opts.Warnings.Record(src.Path(name), "stat", err); err != nil {
+				return err
+			}
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		if sel.excludedWithParents(name) || !sel.selected(src.FS, name) {
+			continue
+		}
+		if ignore != nil && ignoredWithParents(ignore, src.FS, name) {
+			continue
+		}
+
+		if err := emit(src.Path(name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// excludedWithParents reports whether a path or any of its parent directories is excluded
+func (s *selector) excludedWithParents(relPath string) bool {
+	for dir := relPath; dir != "."; dir = path.Dir(dir) {
+		if s.excluded(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredWithParents checks a path against the ignore rules, loading the
+// ignore files of each directory on the way down as a walk would
+func ignoredWithParents(ignore *IgnoreMatcher, fsys fs.FS, relPath string) bool {
+	matcher := *ignore
+	matcher.LoadDir(fsys, ".")
+
+	parts := strings.Split(relPath, "/")
+	for i := range parts {
+		current := strings.Join(parts[:i+1], "/")
+		isDir := i < len(parts)-1
+		if matcher.Match(current, isDir) {
+			return true
+		}
+		if isDir {
+			matcher.LoadDir(fsys, current)
+		}
+	}
+	return false
+}
+
+// NormalizeLanguage maps a user-supplied language name to the name used by DetectLanguage
+func NormalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if alias, ok := languageAliases[lang]; ok {
+		return alias
+	}
+	return lang
+}
+
+// anyDepth makes a pattern without a slash match files in any directory
+func anyDepth(pattern string) string {
+	if strings.Contains(pattern, "/") {
+		return pattern
+	}
+	return "**/" + pattern
+}
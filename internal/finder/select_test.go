@@ -0,0 +1,204 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// testTree is a small project with code, data, a vendored directory and
+// ignore rules at two levels
+var testTree = fstest.MapFS{
+	".gitignore":          {Data: []byte("build/\n*.log\n!keep.log\n")},
+	".git/hooks/hook.py":  {Data: []byte("print(1)\n")},
+	"main.go":             {Data: []byte("package main\n")},
+	"main_test.go":        {Data: []byte("package main\n")},
+	"notes.txt":           {Data: []byte("notes\n")},
+	"debug.log":           {Data: []byte("log\n")},
+	"keep.log":            {Data: []byte("log\n")},
+	"build/out.go":        {Data: []byte("package out\n")},
+	"pkg/util.go":         {Data: []byte("package pkg\n")},
+	"pkg/util.py":         {Data: []byte("print(1)\n")},
+	"pkg/.codeclipignore": {Data: []byte("gen_*.go\n")},
+	"pkg/gen_api.go":      {Data: []byte("package pkg\n")},
+	"vendor/lib/lib.go":   {Data: []byte("package lib\n")},
+	"scripts/run":         {Data: []byte("#!/usr/bin/env python3\nprint(1)\n")},
+}
+
+func TestSelectFilesMapFS(t *testing.T) {
+	tests := []struct {
+		name  string
+		globs []string
+		opts  Options
+		want  []string
+	}{
+		{
+			name: "code files honor ignore rules",
+			want: []string{"main.go", "main_test.go", "pkg/util.go", "pkg/util.py", "vendor/lib/lib.go"},
+		},
+		{
+			name: "no ignore",
+			opts: Options{NoIgnore: true},
+			want: []string{"build/out.go", "main.go", "main_test.go", "pkg/gen_api.go", "pkg/util.go", "pkg/util.py", "vendor/lib/lib.go"},
+		},
+		{
+			name:  "glob with negated ignore rule",
+			globs: []string{"**/*.log"},
+			want:  []string{"keep.log"},
+		},
+		{
+			name: "include without slash matches at any depth",
+			opts: Options{Include: []string{"*.py"}},
+			want: []string{"pkg/util.py"},
+		},
+		{
+			name: "exclude file pattern",
+			opts: Options{Exclude: []string{"*_test.go"}},
+			want: []string{"main.go", "pkg/util.go", "pkg/util.py", "vendor/lib/lib.go"},
+		},
+		{
+			name: "exclude prunes directories",
+			opts: Options{Exclude: []string{"vendor"}},
+			want: []string{"main.go", "main_test.go", "pkg/util.go", "pkg/util.py"},
+		},
+		{
+			name: "include and exclude combine",
+			opts: Options{Include: []string{"*.go"}, Exclude: []string{"pkg/**"}},
+			want: []string{"main.go", "main_test.go", "vendor/lib/lib.go"},
+		},
+		{
+			name: "language detected from shebang",
+			opts: Options{Languages: []string{"py"}},
+			want: []string{"pkg/util.py", "scripts/run"},
+		},
+		{
+			name: "explicit list",
+			opts: Options{Only: []string{"pkg/util.go", "build/out.go", "missing.go", "notes.txt"}},
+			want: []string{"pkg/util.go", "notes.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectFiles(FSSource(testTree, ""), tt.globs, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFilesCodeExtensions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"engine.cc":     {},
+		"engine.cxx":    {},
+		"engine.hh":     {},
+		"module.mts":    {},
+		"module.cts":    {},
+		"schema.proto":  {},
+		"page.tpl":      {},
+		"notes.txt":     {},
+		"LICENSE":       {},
+		"build/Main.kt": {},
+	}
+
+	got, err := SelectFiles(FSSource(fsys, ""), nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"build/Main.kt", "engine.cc", "engine.cxx", "engine.hh", "module.cts", "module.mts", "schema.proto"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SelectFiles() = %q, want %q", got, want)
+	}
+
+	// Extensions registered from a language config count as code too
+	RegisterLanguages(LanguageConfig{Extensions: map[string]string{"tpl": "html"}})
+	t.Cleanup(func() { delete(extensionLanguages, ".tpl") })
+
+	got, err = SelectFiles(FSSource(fsys, ""), nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(got, "page.tpl") {
+		t.Errorf("SelectFiles() = %q, want page.tpl selected once registered", got)
+	}
+}
+
+func TestSelectFilesInvalidPattern(t *testing.T) {
+	_, err := SelectFiles(FSSource(testTree, ""), nil, Options{Exclude: []string{"[a-"}})
+	if err == nil {
+		t.Fatal("SelectFiles() with an invalid exclude pattern succeeded")
+	}
+}
+
+func TestSelectFilesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(target, name string) {
+		t.Helper()
+		if err := os.Symlink(target, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+
+	write("real/a.go", "package real\n")
+	link("real/a.go", "link.go")
+	link("real", "linkdir")
+	link("missing.go", "broken.go")
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []string
+		aliases map[string][]string
+	}{
+		{
+			name: "links to files are kept, links to directories are not entered",
+			want: []string{"broken.go", "link.go", "real/a.go"},
+		},
+		{
+			name:    "following collapses a file to its real location",
+			opts:    Options{FollowSymlinks: true, Warnings: &Warnings{}},
+			want:    []string{"real/a.go"},
+			aliases: map[string][]string{"real/a.go": {"link.go", "linkdir/a.go"}},
+		},
+		{
+			name: "explicit list stats through links",
+			opts: Options{Only: []string{"link.go", "linkdir"}},
+			want: []string{"link.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Aliases = &Aliases{}
+			got, err := SelectFiles(Source{FS: os.DirFS(dir), Dir: dir}, nil, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectFiles() = %q, want %q", got, tt.want)
+			}
+			for canonical, want := range tt.aliases {
+				if got := tt.opts.Aliases.For(canonical); !reflect.DeepEqual(got, want) {
+					t.Errorf("Aliases.For(%q) = %q, want %q", canonical, got, want)
+				}
+			}
+		})
+	}
+}
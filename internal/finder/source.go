@@ -0,0 +1,127 @@
+package finder
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Source is a tree of files to select, read and search. Files are stored in
+// FS under slash-separated names relative to its root, and are presented to
+// the user as display paths prefixed with Root.
+type Source struct {
+	FS   fs.FS  // Files addressed by names relative to the root
+	Root string // Prefix for display paths, such as the --path value
+	Dir  string // Directory on disk backing FS, or "" for virtual sources
+	Rev  string // Git revision the files were read from, or "" for the working tree
+
+	closer io.Closer
+}
+
+// DirSource creates a source for a directory on disk
+func DirSource(dir string) Source {
+	return Source{FS: os.DirFS(dir), Root: dir, Dir: dir}
+}
+
+// FSSource creates a source for any file system, such as an fstest.MapFS.
+// root is only used to build display paths.
+func FSSource(fsys fs.FS, root string) Source {
+	return Source{FS: fsys, Root: root}
+}
+
+// OpenSource opens a directory, or a .zip, .tar, .tar.gz or .tgz archive,
+// as a source. The caller must Close the source when done.
+func OpenSource(path string) (Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Source{}, err
+	}
+	if info.IsDir() {
+		return DirSource(path), nil
+	}
+
+	if IsArchive(path) {
+		fsys, closer, err := openArchive(path)
+		if err != nil {
+			return Source{}, err
+		}
+		return Source{FS: fsys, Root: path, closer: closer}, nil
+	}
+
+	return Source{}, fmt.Errorf("%s is not a directory or a supported archive", path)
+}
+
+// Label returns the name shown for a file in output, annotated with the
+// revision when the files come from git history
+func Label(path, rev string) string {
+	if rev == "" {
+		return path
+	}
+	return path + "@" + rev
+}
+
+// Close releases resources held by the source
+func (s Source) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// Path converts a name within the source to the path shown to the user
+func (s Source) Path(name string) string {
+	if s.Root == "" || s.Root == "." {
+		return filepath.FromSlash(name)
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(name))
+}
+
+// Name converts a display path back to a name within the source
+func (s Source) Name(displayPath string) (string, bool) {
+	if s.Root == "" || s.Root == "." {
+		name := path.Clean(filepath.ToSlash(displayPath))
+		return name, fs.ValidPath(name)
+	}
+
+	rel, err := filepath.Rel(s.Root, displayPath)
+	if err != nil {
+		return "", false
+	}
+	name := filepath.ToSlash(rel)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, fs.ValidPath(name)
+}
+
+// ReadFile reads a file by its display path
+func (s Source) ReadFile(displayPath string) ([]byte, error) {
+	name, ok := s.Name(displayPath)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: displayPath, Err: fs.ErrNotExist}
+	}
+	return fs.ReadFile(s.FS, name)
+}
+
+// Stat describes a file by its display path
+func (s Source) Stat(displayPath string) (fs.FileInfo, error) {
+	name, ok := s.Name(displayPath)
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: displayPath, Err: fs.ErrNotExist}
+	}
+	return fs.Stat(s.FS, name)
+}
+
+// DetectLanguage determines the language of a file in the source from its
+// name and the start of its content
+func (s Source) DetectLanguage(displayPath string) string {
+	name, ok := s.Name(displayPath)
+	if !ok {
+		return Detect(displayPath, nil).Language
+	}
+	return Detect(displayPath, readHeadFS(s.FS, name)).Language
+}
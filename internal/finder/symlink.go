@@ -0,0 +1,206 @@
+package finder
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fileID identifies a file independently of the path it was reached by:
+// device and inode where the platform has them, the resolved path otherwise
+type fileID struct {
+	dev  uint64
+	ino  uint64
+	path string
+}
+
+// Aliases maps the display path of a file reached through symlinks to the
+// other paths it was found under. It is safe for concurrent use, and a nil
+// *Aliases records nothing.
+type Aliases struct {
+	mu    sync.Mutex
+	paths map[string][]string
+}
+
+// add records alias as another name for canonical
+func (a *Aliases) add(canonical, alias string) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.paths == nil {
+		a.paths = make(map[string][]string)
+	}
+	a.paths[canonical] = append(a.paths[canonical], alias)
+}
+
+// For returns the other paths of a file, sorted, or nil if it has none
+func (a *Aliases) For(canonical string) []string {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.paths[canonical]) == 0 {
+		return nil
+	}
+	aliases := append([]string(nil), a.paths[canonical]...)
+	sort.Strings(aliases)
+	return aliases
+}
+
+// walkFollow walks a source on disk like fs.WalkDir, but descends into
+// symlinked directories and passes symlinked files to fn as regular files.
+// A directory that is already being walked higher up is not entered again,
+// which breaks symlink cycles. Broken links are reported to fn as errors.
+func walkFollow(src Source, fn fs.WalkDirFunc) error {
+	info, err := os.Stat(src.Dir)
+	if err != nil {
+		return fn(".", nil, err)
+	}
+
+	err = walkFollowDir(src, ".", fs.FileInfoToDirEntry(info), info, make(map[fileID]bool), fn)
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkFollowDir visits a directory and everything beneath it. ancestors
+// holds the directories on the path from the root.
+func walkFollowDir(src Source, name string, entry fs.DirEntry, info fs.FileInfo, ancestors map[fileID]bool, fn fs.WalkDirFunc) error {
+	if err := fn(name, entry, nil); err != nil {
+		return err
+	}
+
+	if id, ok := fileIDOf(diskPath(src, name), info); ok {
+		ancestors[id] = true
+		defer delete(ancestors, id)
+	}
+
+	entries, err := fs.ReadDir(src.FS, name)
+	if err != nil {
+		// As with fs.WalkDir, fn is called a second time to report the failure
+		if err := fn(name, entry, err); err != nil && err != fs.SkipDir {
+			return err
+		}
+		return nil
+	}
+
+	for _, child := range entries {
+		childName := path.Join(name, child.Name())
+
+		// Everything is stat'ed through links; directories also need an
+		// identity for the cycle check
+		var childInfo fs.FileInfo
+		if child.Type()&fs.ModeSymlink != 0 || child.IsDir() {
+			childInfo, err = os.Stat(diskPath(src, childName))
+			if err != nil {
+				if err := fn(childName, child, err); err != nil && err != fs.SkipDir {
+					return err
+				}
+				continue
+			}
+			child = fs.FileInfoToDirEntry(childInfo)
+		}
+
+		if !child.IsDir() {
+			if err := fn(childName, child, nil); err != nil {
+				if err == fs.SkipDir {
+					return nil
+				}
+				return err
+			}
+			continue
+		}
+
+		if id, ok := fileIDOf(diskPath(src, childName), childInfo); ok && ancestors[id] {
+			continue
+		}
+		if err := walkFollowDir(src, childName, child, childInfo, ancestors, fn); err != nil && err != fs.SkipDir {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diskPath returns the path on disk of a name within a directory source
+func diskPath(src Source, name string) string {
+	return filepath.Join(src.Dir, filepath.FromSlash(name))
+}
+
+// linkTracker collapses files reached under several names to one canonical
+// name: the file's real location when that lies inside the source, or
+// otherwise the first name it was found under
+type linkTracker struct {
+	src     Source
+	root    string            // Resolved absolute path of the source directory
+	seen    map[fileID]string // Canonical names of the files visited so far
+	aliases *Aliases
+}
+
+// newLinkTracker creates a tracker for a directory source
+func newLinkTracker(src Source, aliases *Aliases) *linkTracker {
+	root, err := filepath.Abs(src.Dir)
+	if err == nil {
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+	}
+	return &linkTracker{src: src, root: root, seen: make(map[fileID]string), aliases: aliases}
+}
+
+// visit returns the canonical name for a selected file, and whether the
+// same file was already visited under another name
+func (t *linkTracker) visit(name string) (string, bool) {
+	file := diskPath(t.src, name)
+	info, err := os.Stat(file)
+	if err != nil {
+		return name, false
+	}
+	id, ok := fileIDOf(file, info)
+	if !ok {
+		return name, false
+	}
+
+	canonical, seen := t.seen[id]
+	if !seen {
+		canonical = t.realName(file, name)
+		t.seen[id] = canonical
+	}
+	if canonical != name {
+		t.aliases.add(t.src.Path(canonical), t.src.Path(name))
+	}
+	return canonical, seen
+}
+
+// realName returns the name of a file's resolved location within the
+// source, or name itself when it resolves outside of it
+func (t *linkTracker) realName(file, name string) string {
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return name
+	}
+	resolved, err = filepath.Abs(resolved)
+	if err != nil {
+		return name
+	}
+
+	rel, err := filepath.Rel(t.root, resolved)
+	if err != nil {
+		return name
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") || !fs.ValidPath(rel) {
+		return name
+	}
+	return rel
+}
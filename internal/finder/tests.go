@@ -0,0 +1,194 @@
+package finder
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// testDirs hold tests apart from the code they test, either next to it
+// (src/__tests__) or in a tree of their own (tests/, src/test/java)
+var testDirs = map[string]bool{"__tests__": true, "tests": true, "test": true, "spec": true}
+
+// jsExtensions are the extensions a JavaScript or TypeScript test may use
+// for code written in any of them
+var jsExtensions = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", ".mts", ".cts"}
+
+// cExtensions are the extensions a C or C++ test may use for code written in
+// any of them, headers included
+var cExtensions = []string{".c", ".cc", ".cpp", ".cxx"}
+
+// testNames returns the file names that tests for the file named base take
+// under the conventions of its language
+func testNames(base string) []string {
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	var names []string
+	switch ext {
+	case ".go":
+		names = []string{stem + "_test.go"}
+	case ".py":
+		names = []string{"test_" + stem + ".py", stem + "_test.py"}
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts":
+		for _, kind := range []string{".test", ".spec"} {
+			for _, testExt := range jsExtensions {
+				names = append(names, stem+kind+testExt)
+			}
+		}
+	case ".java", ".kt", ".scala", ".cs", ".php", ".swift":
+		names = []string{stem + "Test" + ext, stem + "Tests" + ext, "Test" + stem + ext, stem + "Spec" + ext, stem + "IT" + ext}
+	case ".rb":
+		names = []string{stem + "_spec.rb", stem + "_test.rb", "test_" + stem + ".rb"}
+	case ".rs":
+		names = []string{stem + "_test.rs", stem + "_tests.rs"}
+	case ".c", ".h", ".cc", ".cpp", ".cxx", ".hpp", ".hh", ".hxx":
+		for _, testExt := range cExtensions {
+			names = append(names, stem+"_test"+testExt, "test_"+stem+testExt, stem+"_unittest"+testExt)
+		}
+	}
+	return names
+}
+
+// IsTestFile reports whether a file holds tests, judging by its name or a
+// test directory it is in
+func IsTestFile(filePath string) bool {
+	slashed := filepath.ToSlash(filePath)
+	base := path.Base(slashed)
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	switch {
+	case ext == ".go":
+		// Go files in a test directory are code like any other
+		return strings.HasSuffix(stem, "_test")
+	case strings.HasPrefix(stem, "test_"),
+		strings.HasSuffix(stem, "_test"),
+		strings.HasSuffix(stem, "_tests"),
+		strings.HasSuffix(stem, "_spec"),
+		strings.HasSuffix(stem, "_unittest"),
+		strings.HasSuffix(stem, ".test"),
+		strings.HasSuffix(stem, ".spec"),
+		strings.HasSuffix(stem, "Test") && stem != "Test",
+		strings.HasSuffix(stem, "Tests") && stem != "Tests":
+		return true
+	}
+
+	for _, segment := range strings.Split(path.Dir(slashed), "/") {
+		if testDirs[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+// PairedTests returns the test files among paths that go with the file at
+// filePath by naming convention: foo_test.go for foo.go, __tests__/foo.test.ts
+// or foo.spec.ts for foo.ts, tests/test_x.py for x.py, FooTest.java for
+// Foo.java. A test file pairs when it sits next to the file, or in a test
+// directory within the file's directory or one of its parents. A test in a
+// test directory that several files of the tree could go with pairs only with
+// the closest of them, so tests/test_util.py goes with util.py rather than
+// with app/util.py as well.
+func PairedTests(filePath string, paths []string) []string {
+	slashed := filepath.ToSlash(filePath)
+	dir := path.Dir(slashed)
+
+	names := make(map[string]bool)
+	for _, name := range testNames(path.Base(slashed)) {
+		names[name] = true
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	var paired []string
+	for _, candidate := range paths {
+		other := filepath.ToSlash(candidate)
+		if other == slashed || !names[path.Base(other)] {
+			continue
+		}
+		otherDir := path.Dir(other)
+		if otherDir == dir {
+			paired = append(paired, candidate)
+			continue
+		}
+		if match, ok := testDirMatch(otherDir, dir); ok && closestTested(other, paths) == match {
+			paired = append(paired, candidate)
+		}
+	}
+	return paired
+}
+
+// testMatch ranks how closely a test directory mirrors the directory of the
+// code it may test
+type testMatch struct {
+	shared int // Trailing directories the two have in common
+	depth  int // Directories between the test directory's parent and the code
+}
+
+// closer reports whether m ranks above other
+func (m testMatch) closer(other testMatch) bool {
+	if m.shared != other.shared {
+		return m.shared > other.shared
+	}
+	return m.depth < other.depth
+}
+
+// testDirMatch reports whether testDir is within a test directory whose
+// parent is dir or one of its ancestors, as tests/app is for app/models, and
+// how closely the directories below the test directory mirror dir
+func testDirMatch(testDir, dir string) (testMatch, bool) {
+	segments := strings.Split(testDir, "/")
+	for i, segment := range segments {
+		if !testDirs[segment] {
+			continue
+		}
+
+		var rel string
+		switch parent := strings.Join(segments[:i], "/"); {
+		case parent == "" || parent == ".":
+			rel = dir
+		case parent == dir:
+			rel = "."
+		case strings.HasPrefix(dir+"/", parent+"/"):
+			rel = strings.TrimPrefix(dir, parent+"/")
+		default:
+			continue
+		}
+
+		var code []string
+		if rel != "." {
+			code = strings.Split(rel, "/")
+		}
+		below := segments[i+1:]
+		match := testMatch{depth: len(code)}
+		for match.shared < len(code) && match.shared < len(below) &&
+			code[len(code)-1-match.shared] == below[len(below)-1-match.shared] {
+			match.shared++
+		}
+		return match, true
+	}
+	return testMatch{}, false
+}
+
+// closestTested returns the best match between the test at testPath and the
+// files among paths it could test by name, outside test directories
+func closestTested(testPath string, paths []string) testMatch {
+	testDir, testName := path.Split(testPath)
+	testDir = path.Clean(testDir)
+
+	var best testMatch
+	found := false
+	for _, candidate := range paths {
+		code := filepath.ToSlash(candidate)
+		if IsTestFile(code) || !slices.Contains(testNames(path.Base(code)), testName) {
+			continue
+		}
+		if match, ok := testDirMatch(testDir, path.Dir(code)); ok && (!found || match.closer(best)) {
+			best, found = match, true
+		}
+	}
+	return best
+}
@@ -0,0 +1,101 @@
+package finder
+
+import (
+	"reflect"
+	"testing"
+)
+
+// pairingTree holds code and tests under the layouts of several languages
+var pairingTree = []string{
+	"finder.go",
+	"finder_test.go",
+	"src/foo.ts",
+	"src/__tests__/foo.test.ts",
+	"src/bar.ts",
+	"src/bar.spec.js",
+	"x.py",
+	"tests/test_x.py",
+	"util.py",
+	"app/util.py",
+	"lib/util.py",
+	"tests/test_util.py",
+	"app/models.py",
+	"lib/models.py",
+	"tests/app/test_models.py",
+	"src/main/java/com/acme/Foo.java",
+	"src/test/java/com/acme/FooTest.java",
+	"pkg/parse.c",
+	"pkg/tests/test_parse.c",
+}
+
+func TestPairedTests(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"finder.go", []string{"finder_test.go"}},
+		{"src/foo.ts", []string{"src/__tests__/foo.test.ts"}},
+		{"src/bar.ts", []string{"src/bar.spec.js"}},
+		{"x.py", []string{"tests/test_x.py"}},
+		{"util.py", []string{"tests/test_util.py"}},
+		{"app/util.py", nil},
+		{"lib/util.py", nil},
+		{"app/models.py", []string{"tests/app/test_models.py"}},
+		{"lib/models.py", nil},
+		{"src/main/java/com/acme/Foo.java", []string{"src/test/java/com/acme/FooTest.java"}},
+		{"pkg/parse.c", []string{"pkg/tests/test_parse.c"}},
+		{"README.md", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := PairedTests(tt.file, pairingTree)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PairedTests(%q) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairedTestsClosestCode(t *testing.T) {
+	// Without util.py at the root, the top-level test goes with the
+	// shallowest util.py, here the one of a src layout
+	paths := []string{"src/pkg/util.py", "src/pkg/sub/util.py", "tests/test_util.py"}
+	if got := PairedTests("src/pkg/util.py", paths); !reflect.DeepEqual(got, []string{"tests/test_util.py"}) {
+		t.Errorf("PairedTests(src/pkg/util.py) = %q", got)
+	}
+	if got := PairedTests("src/pkg/sub/util.py", paths); got != nil {
+		t.Errorf("PairedTests(src/pkg/sub/util.py) = %q, want none", got)
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"finder_test.go", true},
+		{"finder.go", false},
+		{"test/fixtures/data.go", false},
+		{"test_x.py", true},
+		{"x_test.py", true},
+		{"tests/conftest.py", true},
+		{"x.py", false},
+		{"foo.test.ts", true},
+		{"foo.spec.js", true},
+		{"src/__tests__/helpers.ts", true},
+		{"src/foo.ts", false},
+		{"FooTest.java", true},
+		{"FooTests.cs", true},
+		{"Test.java", false},
+		{"user_spec.rb", true},
+		{"parse_unittest.cc", true},
+		{"latest.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTestFile(tt.path); got != tt.want {
+			t.Errorf("IsTestFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package finder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+)
+
+// Warning records a file or directory that couldn't be processed and was left out
+type Warning struct {
+	Path string
+	Op   string // Stage that failed: walk, stat, read, search or headers
+	Err  error
+}
+
+// Error implements the error interface
+func (w Warning) Error() string {
+	return fmt.Sprintf("%s %s: %v", w.Op, w.Path, w.Cause())
+}
+
+// Cause returns the underlying error without the path it is already reported against
+func (w Warning) Cause() error {
+	var pathErr *fs.PathError
+	if errors.As(w.Err, &pathErr) {
+		return pathErr.Err
+	}
+	return w.Err
+}
+
+// Warnings collects problems with individual files so that a run can carry
+// on past them. It is safe for concurrent use. A nil *Warnings collects
+// nothing, and Record hands the error back so the caller stops instead.
+type Warnings struct {
+	mu   sync.Mutex
+	list []Warning
+}
+
+// Record adds a warning and returns nil, or returns err unchanged when w is nil
+func (w *Warnings) Record(path, op string, err error) error {
+	if w == nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.list = append(w.list, Warning{Path: path, Op: op, Err: err})
+	return nil
+}
+
+// List returns the warnings sorted by path, so reports don't depend on the
+// order in which concurrent workers failed
+func (w *Warnings) List() []Warning {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	list := append([]Warning(nil), w.list...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Path < list[j].Path
+	})
+	return list
+}
@@ -0,0 +1,304 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Repo represents a local git repository, accessed through the git command
+type Repo struct {
+	Dir  string // Directory commands run in; may be a subdirectory of the work tree
+	Root string // Top level of the work tree
+}
+
+// Open locates the repository containing dir. Linked worktrees and
+// subdirectories are supported.
+func Open(dir string) (*Repo, error) {
+	repo := &Repo{Dir: dir}
+
+	root, err := repo.run("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %s", dir)
+	}
+	repo.Root = strings.TrimSpace(root)
+
+	return repo, nil
+}
+
+// run executes a git command in the repository directory and returns its stdout
+func (r *Repo) run(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.Dir}, args...)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+
+	return stdout.String(), nil
+}
+
+// runPaths executes a git command that prints NUL-separated paths
+func (r *Repo) runPaths(args ...string) ([]string, error) {
+	out, err := r.run(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, path := range strings.Split(out, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// emptyTree returns the id of the empty tree, used as a base before the first commit
+func (r *Repo) emptyTree() (string, error) {
+	cmd := exec.Command("git", "-C", r.Dir, "hash-object", "-t", "tree", "--stdin")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git hash-object: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// head returns HEAD, or the empty tree when the repository has no commits yet
+func (r *Repo) head() (string, error) {
+	if _, err := r.run("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return r.emptyTree()
+	}
+	return "HEAD", nil
+}
+
+// ChangedFiles lists tracked files whose working tree or staged content differs
+// from HEAD. Paths are relative to Dir and limited to files beneath it.
+func (r *Repo) ChangedFiles() ([]string, error) {
+	base, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+	return r.runPaths("diff", "--name-only", "-z", "--relative", "--diff-filter=d", base)
+}
+
+// StagedFiles lists files whose staged content differs from HEAD
+func (r *Repo) StagedFiles() ([]string, error) {
+	base, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+	return r.runPaths("diff", "--cached", "--name-only", "-z", "--relative", "--diff-filter=d", base)
+}
+
+// UntrackedFiles lists files that aren't tracked and aren't ignored
+func (r *Repo) UntrackedFiles() ([]string, error) {
+	return r.runPaths("ls-files", "--others", "--exclude-standard", "-z")
+}
+
+// FilesSince lists files changed in the working tree since the point where
+// the current branch diverged from ref
+func (r *Repo) FilesSince(ref string) ([]string, error) {
+	base, err := r.run("merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return r.runPaths("diff", "--name-only", "-z", "--relative", "--diff-filter=d", strings.TrimSpace(base))
+}
+
+// MergePaths combines path lists into one sorted list without duplicates
+func MergePaths(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	merged := []string{}
+	for _, list := range lists {
+		for _, path := range list {
+			if !seen[path] {
+				seen[path] = true
+				merged = append(merged, path)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// ResolveRange splits a "base..head" or "base...head" range. With three dots
+// the base becomes the merge base of both sides. A single ref compares that
+// ref against the working tree, signalled by an empty head.
+func (r *Repo) ResolveRange(spec string) (base, head string, err error) {
+	if left, right, found := strings.Cut(spec, "..."); found {
+		if left == "" {
+			left = "HEAD"
+		}
+		if right == "" {
+			right = "HEAD"
+		}
+		mergeBase, err := r.run("merge-base", left, right)
+		if err != nil {
+			return "", "", err
+		}
+		return strings.TrimSpace(mergeBase), right, nil
+	}
+
+	if left, right, found := strings.Cut(spec, ".."); found {
+		if left == "" {
+			left = "HEAD"
+		}
+		if right == "" {
+			right = "HEAD"
+		}
+		return left, right, nil
+	}
+
+	return spec, "", nil
+}
+
+// diffArgs builds the revision arguments for comparing base with head, or
+// with the working tree when head is empty
+func diffArgs(base, head string) []string {
+	if head == "" {
+		return []string{base}
+	}
+	return []string{base, head}
+}
+
+// Change is a file that differs between two revisions
+type Change struct {
+	Status  byte   // Status letter from git diff --name-status: A, D, M, R or T
+	Path    string // Path at head, or at base for a deleted file
+	OldPath string // Path at base of a renamed file; empty otherwise
+}
+
+// DiffFiles lists the files that differ between base and head. A file that
+// was moved, with or without edits, is one rename rather than a deletion and
+// an addition.
+func (r *Repo) DiffFiles(base, head string) ([]Change, error) {
+	args := append([]string{"diff", "--name-status", "-z", "-M", "--relative"}, diffArgs(base, head)...)
+	fields, err := r.runPaths(args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseNameStatus(fields)
+}
+
+// parseNameStatus reads the NUL-separated fields of git diff --name-status -z:
+// a status followed by one path, or by two for renames and copies
+func parseNameStatus(fields []string) ([]Change, error) {
+	changes := []Change{}
+	for i := 0; i < len(fields); {
+		status := fields[i]
+		paths := 1
+		if status[0] == 'R' || status[0] == 'C' {
+			paths = 2
+		}
+		if i+paths >= len(fields) {
+			return nil, fmt.Errorf("git diff: truncated entry for status %s", status)
+		}
+
+		change := Change{Status: status[0], Path: fields[i+paths]}
+		if paths == 2 {
+			change.OldPath = fields[i+1]
+		}
+		changes = append(changes, change)
+		i += paths + 1
+	}
+	return changes, nil
+}
+
+// Diff returns the unified diff of one changed file between base and head,
+// with the given number of context lines. A rename is diffed against its old
+// path.
+func (r *Repo) Diff(base, head string, change Change, context int) (string, error) {
+	args := []string{"diff", "--relative", "-M", fmt.Sprintf("-U%d", context)}
+	args = append(args, diffArgs(base, head)...)
+	args = append(args, "--")
+	if change.OldPath != "" {
+		args = append(args, change.OldPath)
+	}
+	args = append(args, change.Path)
+	return r.run(args...)
+}
+
+// Show returns the content of a file at a revision. path is relative to Dir.
+func (r *Repo) Show(rev, path string) ([]byte, error) {
+	out, err := r.run("show", rev+":./"+filepath.ToSlash(path))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// TreeEntry is a file in a commit's tree
+type TreeEntry struct {
+	Path       string // Relative to Dir, slash-separated
+	Object     string // Blob id
+	Size       int64
+	Executable bool
+}
+
+// ResolveCommit checks that rev names a commit and returns its id
+func (r *Repo) ResolveCommit(rev string) (string, error) {
+	id, err := r.run("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision: %s", rev)
+	}
+	return strings.TrimSpace(id), nil
+}
+
+// ListTree lists the regular files in rev beneath Dir. Symlinks and
+// submodules are left out.
+func (r *Repo) ListTree(rev string) ([]TreeEntry, error) {
+	out, err := r.run("ls-tree", "-r", "-l", "-z", rev)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TreeEntry
+	for _, record := range strings.Split(out, "\x00") {
+		// Each record is "<mode> <type> <object> <size>\t<path>"
+		meta, path, found := strings.Cut(record, "\t")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 4 || fields[1] != "blob" {
+			continue
+		}
+		if fields[0] != "100644" && fields[0] != "100755" {
+			continue
+		}
+
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, TreeEntry{
+			Path:       path,
+			Object:     fields[2],
+			Size:       size,
+			Executable: fields[0] == "100755",
+		})
+	}
+
+	return entries, nil
+}
+
+// ReadBlob returns the content of a blob
+func (r *Repo) ReadBlob(object string) ([]byte, error) {
+	out, err := r.run("cat-file", "blob", object)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
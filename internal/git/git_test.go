@@ -0,0 +1,283 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRepo creates a repository in a temporary directory with one commit on
+// main holding a.go and sub/b.go, and a feature branch checked out
+func testRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	gitCmd(t, dir, "init", "-q", "-b", "main")
+	gitCmd(t, dir, "config", "user.email", "test@example.com")
+	gitCmd(t, dir, "config", "user.name", "test")
+	gitCmd(t, dir, "config", "commit.gpgsign", "false")
+
+	writeFile(t, dir, ".gitignore", "*.log\n")
+	writeFile(t, dir, "a.go", "package a\n")
+	writeFile(t, dir, "sub/b.go", "package sub\n")
+	gitCmd(t, dir, "add", "-A")
+	gitCmd(t, dir, "commit", "-q", "-m", "initial")
+	gitCmd(t, dir, "checkout", "-q", "-b", "feature")
+	return dir
+}
+
+// gitCmd runs git in dir and fails the test when it does
+func gitCmd(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// writeFile writes a file at a slash-separated name within dir
+func writeFile(t *testing.T, dir, name, data string) {
+	t.Helper()
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileLists(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, root string) string // Returns the directory to open
+		list  func(r *Repo) ([]string, error)
+		want  []string
+	}{
+		{
+			name: "changed includes unstaged and staged edits",
+			setup: func(t *testing.T, root string) string {
+				writeFile(t, root, "a.go", "package a\n\nvar x int\n")
+				writeFile(t, root, "sub/b.go", "package sub\n\nvar y int\n")
+				gitCmd(t, root, "add", "sub/b.go")
+				return root
+			},
+			list: (*Repo).ChangedFiles,
+			want: []string{"a.go", "sub/b.go"},
+		},
+		{
+			name: "changed leaves out deleted files",
+			setup: func(t *testing.T, root string) string {
+				gitCmd(t, root, "rm", "-q", "a.go")
+				return root
+			},
+			list: (*Repo).ChangedFiles,
+			want: []string{},
+		},
+		{
+			name: "changed from a subdirectory is relative to it",
+			setup: func(t *testing.T, root string) string {
+				writeFile(t, root, "a.go", "package a\n\nvar x int\n")
+				writeFile(t, root, "sub/b.go", "package sub\n\nvar y int\n")
+				return filepath.Join(root, "sub")
+			},
+			list: (*Repo).ChangedFiles,
+			want: []string{"b.go"},
+		},
+		{
+			name: "staged only",
+			setup: func(t *testing.T, root string) string {
+				writeFile(t, root, "a.go", "package a\n\nvar x int\n")
+				writeFile(t, root, "sub/b.go", "package sub\n\nvar y int\n")
+				gitCmd(t, root, "add", "sub/b.go")
+				return root
+			},
+			list: (*Repo).StagedFiles,
+			want: []string{"sub/b.go"},
+		},
+		{
+			name: "untracked leaves out ignored files",
+			setup: func(t *testing.T, root string) string {
+				writeFile(t, root, "new.go", "package a\n")
+				writeFile(t, root, "sub/new.go", "package sub\n")
+				writeFile(t, root, "debug.log", "log\n")
+				return root
+			},
+			list: (*Repo).UntrackedFiles,
+			want: []string{"new.go", "sub/new.go"},
+		},
+		{
+			name: "untracked from a subdirectory is relative to it",
+			setup: func(t *testing.T, root string) string {
+				writeFile(t, root, "new.go", "package a\n")
+				writeFile(t, root, "sub/new.go", "package sub\n")
+				return filepath.Join(root, "sub")
+			},
+			list: (*Repo).UntrackedFiles,
+			want: []string{"new.go"},
+		},
+		{
+			name: "since covers commits and the working tree",
+			setup: func(t *testing.T, root string) string {
+				writeFile(t, root, "sub/c.go", "package sub\n")
+				gitCmd(t, root, "add", "sub/c.go")
+				gitCmd(t, root, "commit", "-q", "-m", "add c")
+				writeFile(t, root, "a.go", "package a\n\nvar x int\n")
+				return root
+			},
+			list: func(r *Repo) ([]string, error) { return r.FilesSince("main") },
+			want: []string{"a.go", "sub/c.go"},
+		},
+		{
+			name: "since ignores changes made on the other branch",
+			setup: func(t *testing.T, root string) string {
+				gitCmd(t, root, "checkout", "-q", "main")
+				writeFile(t, root, "sub/b.go", "package sub\n\nvar y int\n")
+				gitCmd(t, root, "commit", "-q", "-am", "change b on main")
+				gitCmd(t, root, "checkout", "-q", "feature")
+				writeFile(t, root, "a.go", "package a\n\nvar x int\n")
+				return root
+			},
+			list: func(r *Repo) ([]string, error) { return r.FilesSince("main") },
+			want: []string{"a.go"},
+		},
+		{
+			name: "worktree lists its own changes",
+			setup: func(t *testing.T, root string) string {
+				worktree := filepath.Join(t.TempDir(), "wt")
+				gitCmd(t, root, "worktree", "add", "-q", "-b", "other", worktree, "main")
+				writeFile(t, root, "a.go", "package a\n\nvar x int\n")
+				writeFile(t, worktree, "sub/b.go", "package sub\n\nvar y int\n")
+				return worktree
+			},
+			list: (*Repo).ChangedFiles,
+			want: []string{"sub/b.go"},
+		},
+		{
+			name: "worktree subdirectory",
+			setup: func(t *testing.T, root string) string {
+				worktree := filepath.Join(t.TempDir(), "wt")
+				gitCmd(t, root, "worktree", "add", "-q", "-b", "other", worktree, "main")
+				writeFile(t, worktree, "a.go", "package a\n\nvar x int\n")
+				writeFile(t, worktree, "sub/new.go", "package sub\n")
+				return filepath.Join(worktree, "sub")
+			},
+			list: (*Repo).UntrackedFiles,
+			want: []string{"new.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.setup(t, testRepo(t))
+			repo, err := Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.list(repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedFilesBeforeFirstCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	gitCmd(t, dir, "init", "-q")
+	writeFile(t, dir, "a.go", "package a\n")
+	gitCmd(t, dir, "add", "a.go")
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.ChangedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedFiles() = %q, want %q", got, want)
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	dir := testRepo(t)
+	gitCmd(t, dir, "mv", "sub/b.go", "sub/moved.go")
+	gitCmd(t, dir, "rm", "-q", "a.go")
+	writeFile(t, dir, "c.go", "package c\n\nfunc C() {}\n")
+	gitCmd(t, dir, "add", "c.go")
+	gitCmd(t, dir, "commit", "-q", "-m", "move b")
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.DiffFiles("main", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Status: 'D', Path: "a.go"},
+		{Status: 'A', Path: "c.go"},
+		{Status: 'R', Path: "sub/moved.go", OldPath: "sub/b.go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffFiles() = %+v, want %+v", got, want)
+	}
+
+	diff, err := repo.Diff("main", "feature", want[2], 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "rename from sub/b.go\nrename to sub/moved.go") {
+		t.Errorf("Diff() of a rename = %q, want a rename", diff)
+	}
+}
+
+func TestParseNameStatus(t *testing.T) {
+	got, err := parseNameStatus([]string{"M", "a.go", "R087", "old.go", "new.go", "A", "b.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Status: 'M', Path: "a.go"},
+		{Status: 'R', Path: "new.go", OldPath: "old.go"},
+		{Status: 'A', Path: "b.go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNameStatus() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseNameStatus([]string{"R100", "old.go"}); err == nil {
+		t.Error("parseNameStatus() of a truncated rename succeeded")
+	}
+}
+
+func TestOpenOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("Open() outside a repository succeeded")
+	}
+}
+
+func TestMergePaths(t *testing.T) {
+	got := MergePaths([]string{"b.go", "a.go"}, nil, []string{"a.go", "c.go"})
+	if want := []string{"a.go", "b.go", "c.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MergePaths() = %q, want %q", got, want)
+	}
+}
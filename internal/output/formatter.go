@@ -3,10 +3,13 @@ package output
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/review"
 	"github.com/grant-wade/codeclip/internal/search"
+	"github.com/grant-wade/codeclip/internal/symbols"
 )
 
 // FormatFiles formats file contents with code backticks
@@ -14,7 +17,7 @@ func FormatFiles(files []finder.FileContent) string {
 	var builder strings.Builder
 
 	for _, file := range files {
-		builder.WriteString(fmt.Sprintf("```%s filename=%s\n", file.Language, file.Path))
+		builder.WriteString(fmt.Sprintf("```%s filename=%s%s\n", file.Language, finder.Label(file.Path, file.Rev), aliasesSuffix(file.Aliases)))
 		builder.WriteString(file.Content)
 		builder.WriteString("\n```\n\n")
 	}
@@ -26,13 +29,21 @@ func FormatFiles(files []finder.FileContent) string {
 func FormatSearchResults(results search.SearchResult) string {
 	var builder strings.Builder
 
-	// Collect all snippets by file path for further processing
+	// Collect all snippets by file path for further processing, remembering
+	// the order files were found in so the output is reproducible
 	fileSnippets := make(map[string][]formattingSnippet)
+	preambles := make(map[string]search.Preamble)
+	var order []string
 
 	for _, file := range results.Files {
+		if file.Preamble.Content != "" {
+			preambles[file.Path] = file.Preamble
+		}
+
 		for _, snippet := range file.Snippets {
 			if _, exists := fileSnippets[file.Path]; !exists {
 				fileSnippets[file.Path] = []formattingSnippet{}
+				order = append(order, file.Path)
 			}
 
 			fileSnippets[file.Path] = append(fileSnippets[file.Path], formattingSnippet{
@@ -40,20 +51,36 @@ func FormatSearchResults(results search.SearchResult) string {
 				EndLine:   snippet.EndLine,
 				Content:   snippet.Content,
 				Language:  file.Language,
-				Path:      file.Path,
+				Path:      finder.Label(file.Path, file.Rev),
+				Aliases:   file.Aliases,
+				Score:     snippet.Score,
+				Relation:  snippet.Relation,
 			})
 		}
 	}
 
 	// Process each file's snippets separately
-	for _, snippets := range fileSnippets {
-		// Deduplicate and merge snippets for this file
-		mergedSnippets := mergeFormattingSnippets(snippets)
+	for _, path := range order {
+		// Deduplicate and merge snippets for this file, then restore the
+		// relevance order of fuzzy results
+		mergedSnippets := mergeFormattingSnippets(fileSnippets[path])
+		sort.SliceStable(mergedSnippets, func(i, j int) bool {
+			return mergedSnippets[i].Score > mergedSnippets[j].Score
+		})
+
+		// Open with the file's package clause and imports, unless a snippet
+		// already shows them
+		if preamble, ok := preambles[path]; ok && !showsLines(mergedSnippets, preamble.StartLine, preamble.EndLine) {
+			first := mergedSnippets[0]
+			builder.WriteString(fmt.Sprintf("```%s filename=%s preamble%s\n", first.Language, first.Path, aliasesSuffix(first.Aliases)))
+			builder.WriteString(preamble.Content)
+			builder.WriteString("\n```\n\n")
+		}
 
 		// Add the merged snippets to the output
 		for _, snippet := range mergedSnippets {
-			builder.WriteString(fmt.Sprintf("```%s filename=%s (lines %d-%d)\n",
-				snippet.Language, snippet.Path, snippet.StartLine, snippet.EndLine))
+			builder.WriteString(fmt.Sprintf("```%s filename=%s (lines %d-%d)%s%s\n",
+				snippet.Language, snippet.Path, snippet.StartLine, snippet.EndLine, relationSuffix(snippet.Relation), aliasesSuffix(snippet.Aliases)))
 			builder.WriteString(snippet.Content)
 			builder.WriteString("\n```\n\n")
 		}
@@ -69,6 +96,35 @@ type formattingSnippet struct {
 	Content   string
 	Language  string
 	Path      string
+	Aliases   []string
+	Score     int
+	Relation  string
+}
+
+// showsLines reports whether one of the snippets spans lines start to end
+func showsLines(snippets []formattingSnippet, start, end int) bool {
+	for _, snippet := range snippets {
+		if snippet.StartLine <= start && snippet.EndLine >= end {
+			return true
+		}
+	}
+	return false
+}
+
+// relationSuffix describes a function added around the matches for a code fence header
+func relationSuffix(relation string) string {
+	if relation == "" {
+		return ""
+	}
+	return " " + relation
+}
+
+// aliasesSuffix lists the other paths of a file reached through symlinks for a code fence header
+func aliasesSuffix(aliases []string) string {
+	if len(aliases) == 0 {
+		return ""
+	}
+	return " aliases=" + strings.Join(aliases, ",")
 }
 
 // mergeFormattingSnippets eliminates duplicate snippets and merges overlapping ones
@@ -107,6 +163,8 @@ func mergeFormattingSnippets(snippets []formattingSnippet) []formattingSnippet {
 			}
 
 			// Update previous with merged data
+			previous.Score = max(previous.Score, current.Score)
+			previous.Relation = mergeRelations(previous.Relation, current.Relation)
 			previous.StartLine = newStartLine
 			previous.EndLine = newEndLine
 			previous.Content = newContent
@@ -114,7 +172,8 @@ func mergeFormattingSnippets(snippets []formattingSnippet) []formattingSnippet {
 			// No overlap, add as a new snippet
 			result = append(result, current)
 		} else if current.EndLine > previous.EndLine {
-			previous.EndLine = current.EndLine
+			previous.Score = max(previous.Score, current.Score)
+			previous.Relation = mergeRelations(previous.Relation, current.Relation)
 			// We need to combine contents - this is a simplified approach
 			// In a real implementation you might need to read the file again to get the proper content
 			linesPrevious := strings.Split(previous.Content, "\n")
@@ -122,6 +181,7 @@ func mergeFormattingSnippets(snippets []formattingSnippet) []formattingSnippet {
 
 			// Calculate how many lines to take from current
 			linesToAdd := current.EndLine - previous.EndLine
+			previous.EndLine = current.EndLine
 			if len(linesCurrent) >= linesToAdd {
 				linesPrevious = append(linesPrevious, linesCurrent[len(linesCurrent)-linesToAdd:]...)
 				previous.Content = strings.Join(linesPrevious, "\n")
@@ -132,6 +192,15 @@ func mergeFormattingSnippets(snippets []formattingSnippet) []formattingSnippet {
 	return result
 }
 
+// mergeRelations returns the relation of two merged snippets: a snippet
+// holding a match is not just a related function
+func mergeRelations(a, b string) string {
+	if a == "" || b == "" {
+		return ""
+	}
+	return a
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -145,3 +214,70 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+// FormatReview formats a review bundle: each file's diff followed by the
+// full text of the declarations it touches
+func FormatReview(bundle review.Bundle) string {
+	var builder strings.Builder
+
+	for _, file := range bundle.Files {
+		builder.WriteString(fmt.Sprintf("```diff filename=%s\n", file.Path))
+		builder.WriteString(file.Diff)
+		builder.WriteString("\n```\n\n")
+
+		for _, block := range file.Blocks {
+			name := block.Name
+			if block.Parent != "" {
+				name = block.Parent + "." + block.Name
+			}
+			builder.WriteString(fmt.Sprintf("```%s filename=%s (lines %d-%d) %s %s\n",
+				file.Language, file.Path, block.StartLine, block.EndLine, block.Type, name))
+			builder.WriteString(block.Content)
+			builder.WriteString("\n```\n\n")
+		}
+	}
+
+	return builder.String()
+}
+
+// FormatDefinitions formats symbol definitions, each labelled with its kind and name
+func FormatDefinitions(defs []symbols.Definition) string {
+	var builder strings.Builder
+
+	for _, def := range defs {
+		builder.WriteString(fmt.Sprintf("```%s filename=%s (lines %d-%d) %s %s\n",
+			def.Language, finder.Label(def.Path, def.Rev), def.StartLine, def.EndLine, def.Type, def.QualifiedName()))
+		builder.WriteString(def.Content)
+		builder.WriteString("\n```\n\n")
+	}
+
+	return builder.String()
+}
+
+// FormatReferences formats the code around each usage of a symbol, labelled
+// with the declaration it is in and the lines of the usages
+func FormatReferences(refs []symbols.Reference) string {
+	var builder strings.Builder
+
+	for _, ref := range refs {
+		lines := make([]string, len(ref.Lines))
+		for i, line := range ref.Lines {
+			lines[i] = strconv.Itoa(line)
+		}
+
+		label := "uses at line"
+		if len(lines) > 1 {
+			label = "uses at lines"
+		}
+		if ref.Block != "" {
+			label = ref.Block + " " + label
+		}
+
+		builder.WriteString(fmt.Sprintf("```%s filename=%s (lines %d-%d) %s %s\n",
+			ref.Language, finder.Label(ref.Path, ref.Rev), ref.StartLine, ref.EndLine, label, strings.Join(lines, ",")))
+		builder.WriteString(ref.Content)
+		builder.WriteString("\n```\n\n")
+	}
+
+	return builder.String()
+}
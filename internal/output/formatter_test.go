@@ -0,0 +1,55 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeFormattingSnippets(t *testing.T) {
+	tests := []struct {
+		name     string
+		snippets []formattingSnippet
+		want     []formattingSnippet
+	}{
+		{
+			name: "mostly overlapping",
+			snippets: []formattingSnippet{
+				{StartLine: 1, EndLine: 4, Content: "1\n2\n3\n4"},
+				{StartLine: 2, EndLine: 5, Content: "2\n3\n4\n5"},
+			},
+			want: []formattingSnippet{
+				{StartLine: 1, EndLine: 5, Content: "2\n3\n4\n5"},
+			},
+		},
+		{
+			name: "partly overlapping",
+			snippets: []formattingSnippet{
+				{StartLine: 1, EndLine: 5, Content: "1\n2\n3\n4\n5"},
+				{StartLine: 4, EndLine: 8, Content: "4\n5\n6\n7\n8"},
+			},
+			want: []formattingSnippet{
+				{StartLine: 1, EndLine: 8, Content: "1\n2\n3\n4\n5\n6\n7\n8"},
+			},
+		},
+		{
+			name: "apart",
+			snippets: []formattingSnippet{
+				{StartLine: 6, EndLine: 7, Content: "6\n7"},
+				{StartLine: 1, EndLine: 2, Content: "1\n2"},
+			},
+			want: []formattingSnippet{
+				{StartLine: 1, EndLine: 2, Content: "1\n2"},
+				{StartLine: 6, EndLine: 7, Content: "6\n7"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeFormattingSnippets(tt.snippets)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeFormattingSnippets() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/review"
 	"github.com/grant-wade/codeclip/internal/search"
+	"github.com/grant-wade/codeclip/internal/symbols"
 )
 
 // Stats represents statistics about the copied content
@@ -33,18 +35,39 @@ func CalculateStats(content string) Stats {
 	return stats
 }
 
-// PrintSummary displays a summary of the copied content
-func PrintSummary(stats Stats, files interface{}) {
+// PrintSummary displays a summary of the copied content, followed by any
+// files that were skipped or couldn't be read
+func PrintSummary(stats Stats, files interface{}, warnings []finder.Warning) {
 	bold := color.New(color.Bold)
 	green := color.New(color.FgGreen)
 	blue := color.New(color.FgBlue)
+	yellow := color.New(color.FgYellow)
+	red := color.New(color.FgRed)
 
 	fileCount := 0
+	var skipped []finder.SkippedFile
 	switch v := files.(type) {
 	case []finder.FileContent:
 		fileCount = len(v)
+	case finder.ReadResult:
+		fileCount = len(v.Files)
+		skipped = v.Skipped
 	case search.SearchResult:
 		fileCount = len(v.Files)
+	case review.Bundle:
+		fileCount = len(v.Files)
+	case []symbols.Definition:
+		paths := make(map[string]bool)
+		for _, def := range v {
+			paths[def.Path] = true
+		}
+		fileCount = len(paths)
+	case []symbols.Reference:
+		paths := make(map[string]bool)
+		for _, ref := range v {
+			paths[ref.Path] = true
+		}
+		fileCount = len(paths)
 	}
 
 	bold.Println("\n📋 Codeclip Summary:")
@@ -54,6 +77,22 @@ func PrintSummary(stats Stats, files interface{}) {
 	fmt.Printf("  Characters: %d\n", stats.CharCount)
 	blue.Printf("  Est. Tokens: %d\n\n", stats.EstimatedTokens)
 
+	if len(skipped) > 0 {
+		yellow.Printf("  Skipped %d file(s):\n", len(skipped))
+		for _, file := range skipped {
+			yellow.Printf("    %s (%s: %s)\n", file.Path, file.Reason, file.Detail)
+		}
+		fmt.Println()
+	}
+
+	if len(warnings) > 0 {
+		red.Printf("  Could not read %d file(s):\n", len(warnings))
+		for _, warning := range warnings {
+			red.Printf("    %s (%s: %v)\n", warning.Path, warning.Op, warning.Cause())
+		}
+		fmt.Println()
+	}
+
 	if stats.SnippetCount > 0 {
 		green.Println("✓ Code successfully copied!")
 	}
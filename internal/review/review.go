@@ -0,0 +1,187 @@
+package review
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/git"
+)
+
+// hunkHeader matches the "@@ -a,b +c,d @@" line that starts a diff hunk
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// Bundle is the review material for a range of commits
+type Bundle struct {
+	Range string
+	Files []File
+}
+
+// File holds the diff of one changed file and the declarations it touches
+type File struct {
+	Path     string
+	Language string
+	Diff     string
+	Blocks   []Block
+}
+
+// Block is the complete current text of a function or type touched by the diff
+type Block struct {
+	Type      finder.HeaderType
+	Name      string
+	Parent    string
+	StartLine int
+	EndLine   int
+	Content   string
+}
+
+// Options controls how the bundle is built
+type Options struct {
+	ContextLines int            // Context lines around each diff hunk
+	Selection    finder.Options // Include, exclude and language selectors narrowing the changed files
+
+	// Warnings collects changed files whose current text couldn't be read,
+	// which are then bundled with their diff alone; when nil the first such
+	// error stops the build
+	Warnings *finder.Warnings
+}
+
+// Build collects the diff for every file changed in spec ("base..head",
+// "base...head" or a single ref compared with the working tree) along with
+// the declarations the changes fall in
+func Build(repo *git.Repo, spec string, opts Options) (Bundle, error) {
+	bundle := Bundle{Range: spec}
+
+	base, head, err := repo.ResolveRange(spec)
+	if err != nil {
+		return bundle, err
+	}
+
+	changes, err := repo.DiffFiles(base, head)
+	if err != nil {
+		return bundle, err
+	}
+
+	matcher, err := finder.NewPathMatcher(opts.Selection)
+	if err != nil {
+		return bundle, err
+	}
+
+	for _, change := range changes {
+		path := change.Path
+		if !matcher.Match(path) {
+			continue
+		}
+
+		diff, err := repo.Diff(base, head, change, opts.ContextLines)
+		if err != nil {
+			return bundle, err
+		}
+
+		file := File{
+			Path:     filepath.Join(repo.Dir, path),
+			Language: finder.DetectLanguage(path),
+			Diff:     strings.TrimRight(diff, "\n"),
+		}
+
+		// A deleted file has no declarations left to show
+		if change.Status != 'D' {
+			content, err := readHead(repo, head, path)
+			if err != nil {
+				if err := opts.Warnings.Record(file.Path, "read", err); err != nil {
+					return bundle, err
+				}
+			} else {
+				file.Language = finder.DetectLanguageContent(path, content)
+				file.Blocks = touchedBlocks(path, content, changedLines(diff))
+			}
+		}
+
+		bundle.Files = append(bundle.Files, file)
+	}
+
+	return bundle, nil
+}
+
+// readHead reads a file as it is at head, or from the working tree when head is empty
+func readHead(repo *git.Repo, head, path string) ([]byte, error) {
+	if head == "" {
+		return os.ReadFile(filepath.Join(repo.Dir, path))
+	}
+	return repo.Show(head, path)
+}
+
+// changedLines returns the new-side line numbers touched by a unified diff.
+// Removed lines count against the line that now sits in their place.
+func changedLines(diff string) []int {
+	var changed []int
+	newLine := 0
+	inHunk := false
+
+	for _, line := range strings.Split(diff, "\n") {
+		if match := hunkHeader.FindStringSubmatch(line); match != nil {
+			newLine, _ = strconv.Atoi(match[1])
+			inHunk = true
+			continue
+		}
+		if !inHunk || line == "" {
+			continue
+		}
+
+		switch line[0] {
+		case '+':
+			changed = append(changed, newLine)
+			newLine++
+		case '-':
+			changed = append(changed, max(newLine, 1))
+		case ' ':
+			newLine++
+		}
+	}
+
+	return changed
+}
+
+// touchedBlocks finds the innermost function or type around each changed line
+func touchedBlocks(path string, content []byte, changed []int) []Block {
+	if len(changed) == 0 {
+		return nil
+	}
+
+	headers, err := finder.ParseHeaders(path, content)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(string(content), "\n")
+
+	picked := make(map[int]bool)
+	for _, line := range changed {
+		if best := finder.EnclosingBlock(headers, line); best != -1 {
+			picked[best] = true
+		}
+	}
+
+	var blocks []Block
+	for i := range picked {
+		header := headers[i]
+		end := min(header.EndLine, len(lines))
+		blocks = append(blocks, Block{
+			Type:      header.Type,
+			Name:      header.Name,
+			Parent:    header.Parent,
+			StartLine: header.LineNum,
+			EndLine:   end,
+			Content:   strings.Join(lines[header.LineNum-1:end], "\n"),
+		})
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].StartLine < blocks[j].StartLine
+	})
+
+	return blocks
+}
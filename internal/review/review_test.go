@@ -0,0 +1,181 @@
+package review
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/git"
+)
+
+func TestChangedLines(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []int
+	}{
+		{
+			name: "added lines",
+			diff: "--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,4 @@\n package a\n+\n+var x int\n func f() {}\n",
+			want: []int{2, 3},
+		},
+		{
+			name: "removed lines count against the line in their place",
+			diff: "@@ -3,3 +3,2 @@\n a\n-b\n c\n",
+			want: []int{4},
+		},
+		{
+			name: "replaced line",
+			diff: "@@ -5 +5 @@\n-old\n+new\n",
+			want: []int{5, 5},
+		},
+		{
+			name: "removal at the top of the file",
+			diff: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+			want: []int{1, 1},
+		},
+		{
+			name: "several hunks",
+			diff: "@@ -2,2 +2,3 @@\n a\n+b\n c\n@@ -20,2 +21,2 @@\n x\n-y\n+z\n",
+			want: []int{3, 22, 22},
+		},
+		{
+			name: "file headers before the first hunk are skipped",
+			diff: "diff --git a/a.go b/a.go\nindex 1..2 100644\n--- a/a.go\n+++ b/a.go\n",
+			want: nil,
+		},
+		{
+			name: "no newline marker",
+			diff: "@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n",
+			want: []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changedLines(tt.diff); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changedLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTouchedBlocks(t *testing.T) {
+	content := []byte("package a\n\nfunc F() {\n\treturn\n}\n\nfunc G() {\n\treturn\n}\n")
+
+	got := touchedBlocks("a.go", content, []int{1, 4, 8, 8})
+	var names []string
+	for _, block := range got {
+		names = append(names, block.Name)
+	}
+	if want := []string{"F", "G"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("touchedBlocks() = %q, want %q", names, want)
+	}
+}
+
+// testRepo creates a repository with a commit on main and a feature branch
+// that renames one file, deletes another and edits a function in a third
+func testRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	run("config", "user.email", "test@example.com")
+	run("config", "user.name", "test")
+	run("config", "commit.gpgsign", "false")
+	write("old.go", "package a\n\nfunc Moved() {\n\tprintln(\"one\")\n\tprintln(\"two\")\n\tprintln(\"three\")\n}\n")
+	write("gone.go", "package a\n\nfunc Gone() {}\n")
+	write("edit.go", "package a\n\nfunc Edit() {\n\tprintln(1)\n}\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+
+	run("checkout", "-q", "-b", "feature")
+	run("mv", "old.go", "new.go")
+	run("rm", "-q", "gone.go")
+	write("edit.go", "package a\n\nfunc Edit() {\n\tprintln(2)\n}\n")
+	run("commit", "-q", "-am", "feature")
+	return dir
+}
+
+func TestBuild(t *testing.T) {
+	dir := testRepo(t)
+	repo, err := git.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := Build(repo, "main..feature", Options{ContextLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]string)
+	for _, file := range bundle.Files {
+		var blocks []string
+		for _, block := range file.Blocks {
+			blocks = append(blocks, block.Name)
+		}
+		got[filepath.Base(file.Path)] = blocks
+	}
+	want := map[string][]string{
+		"edit.go": {"Edit"},
+		"gone.go": nil,
+		"new.go":  nil, // Moved without edits, so no lines changed
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() files = %q, want %q", got, want)
+	}
+}
+
+func TestBuildUnreadableFile(t *testing.T) {
+	dir := testRepo(t)
+	repo, err := git.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A symlink to itself is a change git can diff but can't be read back
+	edit := filepath.Join(dir, "edit.go")
+	if err := os.Remove(edit); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("edit.go", edit); err != nil {
+		t.Skip("symlinks not supported")
+	}
+
+	if _, err := Build(repo, "HEAD", Options{}); err == nil {
+		t.Error("Build() without warnings succeeded on an unreadable file")
+	}
+
+	warnings := &finder.Warnings{}
+	bundle, err := Build(repo, "HEAD", Options{Warnings: warnings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Files) != 1 {
+		t.Errorf("Build() bundled %d files, want the unreadable one with its diff", len(bundle.Files))
+	}
+	if list := warnings.List(); len(list) != 1 || list[0].Path != edit {
+		t.Errorf("Build() warnings = %v, want one for %s", list, edit)
+	}
+}
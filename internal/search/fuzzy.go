@@ -0,0 +1,141 @@
+package search
+
+import (
+	"unicode"
+)
+
+// Fuzzy scoring constants. Every matched character earns fuzzyMatch, gaps
+// between matched characters cost fuzzyGapStart plus fuzzyGapExtension for
+// each further skipped character, and characters at the start of a word or a
+// camelCase hump earn a bonus, doubled for the first character of the pattern.
+const (
+	fuzzyMatch            = 16
+	fuzzyGapStart         = -3
+	fuzzyGapExtension     = -1
+	fuzzyBonusBoundary    = 8
+	fuzzyBonusCamel       = 7
+	fuzzyBonusConsecutive = 4
+	fuzzyFirstMultiplier  = 2
+
+	// fuzzyMaxLineLength bounds the work done on very long (usually minified) lines
+	fuzzyMaxLineLength = 2000
+)
+
+// fuzzyMatcher scores lines against a pattern whose characters must appear
+// in order, ignoring case, but not necessarily next to each other
+type fuzzyMatcher struct {
+	pattern []rune
+}
+
+// newFuzzyMatcher creates a matcher for pattern
+func newFuzzyMatcher(pattern string) *fuzzyMatcher {
+	runes := []rune(pattern)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return &fuzzyMatcher{pattern: runes}
+}
+
+// score returns the best alignment score of the pattern in line, and false
+// when the pattern doesn't occur or only does so spread thinly across it
+func (f *fuzzyMatcher) score(line string) (int, bool) {
+	text := []rune(line)
+	if len(text) > fuzzyMaxLineLength {
+		text = text[:fuzzyMaxLineLength]
+	}
+	n, m := len(f.pattern), len(text)
+	if n == 0 || n > m || !f.isSubsequence(text) {
+		return 0, false
+	}
+
+	bonus := make([]int, m)
+	for j := range text {
+		bonus[j] = charBonus(text, j)
+	}
+
+	// prev[j] is the best score with the previous pattern character matched
+	// at text[j], or noScore when it can't be
+	const noScore = -1 << 30
+	prev := make([]int, m)
+	curr := make([]int, m)
+
+	for i, p := range f.pattern {
+		gapBest := noScore // Best prev[k] + gap penalty for k < j-1
+		for j := 0; j < m; j++ {
+			if gapBest > noScore {
+				gapBest += fuzzyGapExtension
+			}
+			if j >= 2 && prev[j-2] > noScore {
+				gapBest = max(gapBest, prev[j-2]+fuzzyGapStart)
+			}
+
+			curr[j] = noScore
+			if unicode.ToLower(text[j]) != p {
+				continue
+			}
+
+			if i == 0 {
+				// Leading text before the first match isn't penalised
+				curr[j] = fuzzyMatch + bonus[j]*fuzzyFirstMultiplier
+				continue
+			}
+
+			best := gapBest
+			if j >= 1 && prev[j-1] > noScore {
+				best = max(best, prev[j-1]+fuzzyBonusConsecutive)
+			}
+			if best > noScore {
+				curr[j] = best + fuzzyMatch + bonus[j]
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	score := noScore
+	for _, s := range prev {
+		score = max(score, s)
+	}
+
+	// Reject matches where gaps ate more than half of what the characters earned
+	if score < n*fuzzyMatch/2 {
+		return 0, false
+	}
+	return score, true
+}
+
+// isSubsequence is a quick check that every pattern character occurs in order
+func (f *fuzzyMatcher) isSubsequence(text []rune) bool {
+	i := 0
+	for _, r := range text {
+		if i < len(f.pattern) && unicode.ToLower(r) == f.pattern[i] {
+			i++
+		}
+	}
+	return i == len(f.pattern)
+}
+
+// charBonus rewards characters that start a word or a camelCase hump
+func charBonus(text []rune, j int) int {
+	curr := text[j]
+	if !isWordChar(curr) {
+		return 0
+	}
+	if j == 0 || !isWordChar(text[j-1]) {
+		return fuzzyBonusBoundary
+	}
+
+	prev := text[j-1]
+	if unicode.IsLower(prev) && unicode.IsUpper(curr) {
+		return fuzzyBonusCamel
+	}
+	if unicode.IsLetter(prev) && unicode.IsDigit(curr) {
+		return fuzzyBonusCamel
+	}
+	return 0
+}
+
+// isWordChar reports whether r is part of an identifier, not counting "_"
+// which separates words in snake_case names
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
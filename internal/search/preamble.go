@@ -0,0 +1,99 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// Preamble is the package or module declaration and imports at the top of a
+// file, which tell a reader of its snippets where they live and what the
+// names they use refer to
+type Preamble struct {
+	StartLine int
+	EndLine   int
+	Content   string // The declarations as written, without the lines between them
+}
+
+// AddPreambles sets the preamble of every file in results. Files that can't
+// be read are reported to warnings, or stop it when that is nil.
+func AddPreambles(src finder.Source, results SearchResult, warnings *finder.Warnings) (SearchResult, error) {
+	for i, file := range results.Files {
+		data, err := src.ReadFile(file.Path)
+		if err != nil {
+			if err := warnings.Record(file.Path, "read", err); err != nil {
+				return results, err
+			}
+			continue
+		}
+		results.Files[i].Preamble = FilePreamble(file.Path, data)
+	}
+	return results, nil
+}
+
+// FilePreamble collects the Package and Import declarations of a file's
+// headers. Imports inside functions and other indented blocks are left out.
+func FilePreamble(path string, data []byte) Preamble {
+	headers, _ := finder.ParseHeaders(path, data)
+	lines := strings.Split(string(data), "\n")
+	masked := finder.MaskCode(finder.DetectLanguageContent(path, data), lines)
+
+	var preamble Preamble
+	var kept []string
+	next := 1 // First line not yet in the preamble
+	afterPackage := false
+	for _, header := range headers {
+		if header.Type != finder.Package && header.Type != finder.Import {
+			continue
+		}
+		start := header.LineNum
+		if start < next || start > len(lines) || indented(lines[start-1]) {
+			continue
+		}
+
+		end := header.EndLine
+		if end < start {
+			end = statementEnd(masked, start)
+		}
+		end = min(end, len(lines))
+
+		if preamble.StartLine == 0 {
+			preamble.StartLine = start
+		} else if afterPackage {
+			kept = append(kept, "") // Set the package clause apart from the imports
+		}
+		afterPackage = header.Type == finder.Package
+		preamble.EndLine = end
+		kept = append(kept, lines[start-1:end]...)
+		next = end + 1
+	}
+
+	preamble.Content = strings.Join(kept, "\n")
+	return preamble
+}
+
+// indented reports whether a line starts with whitespace
+func indented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// statementEnd returns the line where a declaration starting at line ends,
+// following brackets opened on it across lines as in "from x import (" or
+// a multi-line JavaScript import
+func statementEnd(masked []string, line int) int {
+	depth := 0
+	for i := line - 1; i < len(masked); i++ {
+		for _, r := range masked[i] {
+			switch r {
+			case '(', '{', '[':
+				depth++
+			case ')', '}', ']':
+				depth--
+			}
+		}
+		if depth <= 0 {
+			return i + 1
+		}
+	}
+	return line
+}
@@ -0,0 +1,65 @@
+package search
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// preambleFiles hold package clauses and imports in several languages, with
+// code, comments and nested imports around them
+var preambleFiles = map[string]string{
+	"main.go":  "// Command main\npackage main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nimport \"strings\"\n\nfunc main() {}\n",
+	"app.py":   "\"\"\"Doc.\"\"\"\nimport os\nfrom x import (\n    a,\n    b,\n)\n\nX = 1\nimport late\n\ndef f():\n    import inner\n",
+	"app.ts":   "import {\n  a,\n  b,\n} from \"./a\";\nimport c from \"c\";\n\nexport const d = 1;\n",
+	"parse.c":  "#ifndef PARSE_H\n#define PARSE_H\n#include <stdio.h>\n#include \"parse.h\"\n// #include \"commented.h\"\n\nint parse(void);\n",
+	"Foo.java": "package com.acme;\n\nimport java.util.List;\nimport java.util.Map;\n\nclass Foo {}\n",
+	"notes.md": "# Notes\n",
+}
+
+func TestFilePreamble(t *testing.T) {
+	tests := []struct {
+		file string
+		want Preamble
+	}{
+		{"main.go", Preamble{2, 9, "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\nimport \"strings\""}},
+		// Imports after code still count, but not those inside functions
+		{"app.py", Preamble{2, 9, "import os\nfrom x import (\n    a,\n    b,\n)\nimport late"}},
+		{"app.ts", Preamble{1, 5, "import {\n  a,\n  b,\n} from \"./a\";\nimport c from \"c\";"}},
+		// Include guards and commented-out includes are left out
+		{"parse.c", Preamble{3, 4, "#include <stdio.h>\n#include \"parse.h\""}},
+		{"Foo.java", Preamble{1, 4, "package com.acme;\n\nimport java.util.List;\nimport java.util.Map;"}},
+		{"notes.md", Preamble{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := FilePreamble(tt.file, []byte(preambleFiles[tt.file])); got != tt.want {
+				t.Errorf("FilePreamble() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddPreambles(t *testing.T) {
+	fsys := fstest.MapFS{"main.go": {Data: []byte(preambleFiles["main.go"])}}
+	src := finder.FSSource(fsys, "")
+	results := SearchResult{Files: []SearchFile{{Path: "main.go"}, {Path: "gone.go"}}}
+
+	if _, err := AddPreambles(src, results, nil); err == nil {
+		t.Error("AddPreambles() without warnings succeeded on a missing file")
+	}
+
+	warnings := &finder.Warnings{}
+	got, err := AddPreambles(src, results, warnings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Files[0].Preamble.StartLine != 2 || got.Files[1].Preamble != (Preamble{}) {
+		t.Errorf("AddPreambles() preambles = %+v, %+v", got.Files[0].Preamble, got.Files[1].Preamble)
+	}
+	if list := warnings.List(); len(list) != 1 || list[0].Path != "gone.go" {
+		t.Errorf("AddPreambles() warnings = %v, want one for gone.go", list)
+	}
+}
@@ -0,0 +1,576 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed boolean search query. Terms are combined with AND, OR,
+// NOT and NEAR/n, where "a NEAR/3 b" requires a line matching a within three
+// lines of one matching b. Adjacent terms are joined with AND, parentheses
+// group, and double quotes keep spaces or operator words in a term. Operators
+// bind from loosest to tightest as OR, AND, NOT, NEAR.
+type Query struct {
+	root  queryNode
+	terms []*termNode
+}
+
+// queryNode is one node of a parsed query
+type queryNode interface {
+	// eval reports whether the node holds for lines start..end (0-based,
+	// inclusive) and which lines made it hold
+	eval(matches termMatches, start, end int) (bool, hitSet)
+}
+
+// termMatches holds, for each term of a query, the sorted lines of a file
+// it matches, and the best fuzzy score of each matching line
+type termMatches struct {
+	lines  [][]int
+	scores map[int]int
+	spans  map[int]int // Last line of the longest multiline match starting on a line
+}
+
+// hitSet maps a line to the patterns that matched on it
+type hitSet map[int][]string
+
+// termNode matches lines against a single pattern
+type termNode struct {
+	pattern string
+	match   func(line string) (score int, ok bool)
+	regex   *regexp.Regexp // nil for fuzzy terms
+	index   int
+
+	// next matches a whole word right after a non-word character, without
+	// the start of the text, to resume a --word search past an earlier match
+	next *regexp.Regexp
+}
+
+// newTerm compiles a pattern into the term at index, using a fuzzy scorer
+// or a regular expression depending on opts
+func newTerm(pattern string, index int, opts Options) (*termNode, error) {
+	term := &termNode{pattern: pattern, index: index}
+
+	if opts.FuzzySearch {
+		term.match = newFuzzyMatcher(pattern).score
+		return term, nil
+	}
+
+	regex, err := compilePattern(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+	term.regex = regex
+	term.match = func(line string) (int, bool) {
+		return 0, regex.MatchString(line)
+	}
+
+	if opts.WholeWord {
+		if term.next, err = compileWithin(pattern, opts, `\W`); err != nil {
+			return nil, err
+		}
+	}
+	return term, nil
+}
+
+// findAll returns the start and end offsets of every match of the term in
+// content. Whole-word matches are reported without the characters around
+// them, and a non-word character can end one match and start the next.
+func (t *termNode) findAll(content string) [][]int {
+	if t.next == nil {
+		return t.regex.FindAllStringIndex(content, -1)
+	}
+
+	var found [][]int
+	regex, from := t.regex, 0
+	for from <= len(content) {
+		loc := regex.FindStringSubmatchIndex(content[from:])
+		if loc == nil {
+			break
+		}
+		start, end := from+loc[2], from+loc[3]
+		found = append(found, []int{start, end})
+
+		// Resume on the last character of the match, which the next one may
+		// only use as its leading boundary
+		if end == start {
+			end++ // An empty match would be found again
+		}
+		if end > len(content) {
+			break
+		}
+		regex, from = t.next, end-1
+	}
+	return found
+}
+
+type andNode struct{ left, right queryNode }
+type orNode struct{ left, right queryNode }
+type notNode struct{ operand queryNode }
+
+type nearNode struct {
+	left, right queryNode
+	distance    int
+}
+
+// ParseQuery parses a query. Terms are compiled with the pattern settings
+// in opts, so --fixed-strings, --ignore-case and --word apply to each term.
+func ParseQuery(text string, opts Options) (*Query, error) {
+	tokens, err := tokenizeQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	p := &queryParser{tokens: tokens, opts: opts, query: &Query{}}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q in query", p.peek().text)
+	}
+
+	p.query.root = root
+	return p.query, nil
+}
+
+// patternsQuery builds the query that matches any of patterns
+func patternsQuery(patterns []string, opts Options) (*Query, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no search pattern given")
+	}
+
+	query := &Query{}
+	for _, pattern := range patterns {
+		term, err := newTerm(pattern, len(query.terms), opts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		query.terms = append(query.terms, term)
+		if query.root == nil {
+			query.root = term
+		} else {
+			query.root = orNode{left: query.root, right: term}
+		}
+	}
+	return query, nil
+}
+
+// match finds the lines each term matches
+func (q *Query) match(lines []string) termMatches {
+	matches := termMatches{
+		lines:  make([][]int, len(q.terms)),
+		scores: make(map[int]int),
+	}
+	for i, line := range lines {
+		for _, term := range q.terms {
+			if score, ok := term.match(line); ok {
+				matches.lines[term.index] = append(matches.lines[term.index], i)
+				matches.scores[i] = max(matches.scores[i], score)
+			}
+		}
+	}
+	return matches
+}
+
+// matchMultiline finds the matches of each term in the whole content of a
+// file. A match is recorded against the line it starts on, and the line it
+// ends on is kept in spans.
+func (q *Query) matchMultiline(content string, lineCount int) termMatches {
+	matches := termMatches{
+		lines:  make([][]int, len(q.terms)),
+		scores: make(map[int]int),
+		spans:  make(map[int]int),
+	}
+	if lineCount == 0 {
+		return matches
+	}
+
+	index := newLineIndex(content)
+	for _, term := range q.terms {
+		var termLines []int
+		for _, loc := range term.findAll(content) {
+			start := min(index.line(loc[0]), lineCount-1)
+			end := start
+			if loc[1] > loc[0] {
+				end = min(index.line(loc[1]-1), lineCount-1)
+			}
+
+			if len(termLines) == 0 || termLines[len(termLines)-1] != start {
+				termLines = append(termLines, start)
+			}
+			matches.spans[start] = max(matches.spans[start], end)
+		}
+		matches.lines[term.index] = termLines
+	}
+	return matches
+}
+
+// spanEnd returns the last line of the match starting on line
+func (matches termMatches) spanEnd(line int) int {
+	if end, ok := matches.spans[line]; ok {
+		return end
+	}
+	return line
+}
+
+// lineIndex holds the byte offset at which each line of a file starts
+type lineIndex []int
+
+// newLineIndex indexes the lines of content
+func newLineIndex(content string) lineIndex {
+	index := lineIndex{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			index = append(index, i+1)
+		}
+	}
+	return index
+}
+
+// line returns the 0-based line containing a byte offset
+func (index lineIndex) line(offset int) int {
+	return sort.Search(len(index), func(i int) bool {
+		return index[i] > offset
+	}) - 1
+}
+
+// best returns the highest score among lines
+func (matches termMatches) best(lines []int) int {
+	best := 0
+	for _, line := range lines {
+		best = max(best, matches.scores[line])
+	}
+	return best
+}
+
+// candidates returns every line matched by any term, in order
+func (matches termMatches) candidates() []int {
+	seen := make(map[int]bool)
+	var lines []int
+	for _, termLines := range matches.lines {
+		for _, line := range termLines {
+			if !seen[line] {
+				seen[line] = true
+				lines = append(lines, line)
+			}
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// within returns the lines of a sorted list that fall in start..end
+func within(lines []int, start, end int) []int {
+	from := sort.SearchInts(lines, start)
+	to := sort.SearchInts(lines, end+1)
+	return lines[from:to]
+}
+
+func (t *termNode) eval(matches termMatches, start, end int) (bool, hitSet) {
+	lines := within(matches.lines[t.index], start, end)
+	if len(lines) == 0 {
+		return false, nil
+	}
+	hits := make(hitSet, len(lines))
+	for _, line := range lines {
+		hits[line] = []string{t.pattern}
+	}
+	return true, hits
+}
+
+func (n andNode) eval(matches termMatches, start, end int) (bool, hitSet) {
+	ok, left := n.left.eval(matches, start, end)
+	if !ok {
+		return false, nil
+	}
+	ok, right := n.right.eval(matches, start, end)
+	if !ok {
+		return false, nil
+	}
+	return true, left.union(right)
+}
+
+func (n orNode) eval(matches termMatches, start, end int) (bool, hitSet) {
+	leftOK, left := n.left.eval(matches, start, end)
+	rightOK, right := n.right.eval(matches, start, end)
+	return leftOK || rightOK, left.union(right)
+}
+
+func (n notNode) eval(matches termMatches, start, end int) (bool, hitSet) {
+	ok, _ := n.operand.eval(matches, start, end)
+	return !ok, nil
+}
+
+func (n nearNode) eval(matches termMatches, start, end int) (bool, hitSet) {
+	leftOK, left := n.left.eval(matches, start, end)
+	rightOK, right := n.right.eval(matches, start, end)
+	if !leftOK || !rightOK {
+		return false, nil
+	}
+
+	// Keep the lines on each side that have a partner on the other side
+	hits := make(hitSet)
+	for leftLine, leftPatterns := range left {
+		for rightLine, rightPatterns := range right {
+			if abs(leftLine-rightLine) <= n.distance {
+				hits.add(leftLine, leftPatterns)
+				hits.add(rightLine, rightPatterns)
+			}
+		}
+	}
+	return len(hits) > 0, hits
+}
+
+// add records patterns as matching on line
+func (h hitSet) add(line int, patterns []string) {
+	h[line] = mergePatterns(h[line], patterns)
+}
+
+// union combines two hit sets into a new one
+func (h hitSet) union(other hitSet) hitSet {
+	merged := make(hitSet, len(h)+len(other))
+	for line, patterns := range h {
+		merged.add(line, patterns)
+	}
+	for line, patterns := range other {
+		merged.add(line, patterns)
+	}
+	return merged
+}
+
+// lines returns the lines of the hit set in order
+func (h hitSet) lines() []int {
+	lines := make([]int, 0, len(h))
+	for line := range h {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// patterns returns every pattern in the hit set, in line order
+func (h hitSet) patterns() []string {
+	var patterns []string
+	for _, line := range h.lines() {
+		patterns = mergePatterns(patterns, h[line])
+	}
+	return patterns
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// queryToken is a lexical token of a query
+type queryToken struct {
+	text   string
+	quoted bool // Quoted terms are never operators or parentheses
+}
+
+// isOperator reports whether the token is the given unquoted operator word
+func (t queryToken) isOperator(op string) bool {
+	return !t.quoted && t.text == op
+}
+
+// nearDistance returns n for a NEAR/n operator
+func (t queryToken) nearDistance() (int, bool) {
+	if t.quoted {
+		return 0, false
+	}
+	rest, found := strings.CutPrefix(t.text, "NEAR/")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// tokenizeQuery splits a query into terms, operators and parentheses. An
+// unquoted term runs to the next space and may contain balanced
+// parentheses, so calls such as ctx.Done() need no quoting.
+func tokenizeQuery(text string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(' || r == ')':
+			tokens = append(tokens, queryToken{text: string(r)})
+			i++
+
+		case r == '"':
+			var term strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				term.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated quote in query")
+			}
+			i++
+			tokens = append(tokens, queryToken{text: term.String(), quoted: true})
+
+		default:
+			start := i
+			depth := 0
+			for ; i < len(runes) && !unicode.IsSpace(runes[i]); i++ {
+				if runes[i] == '(' {
+					depth++
+				} else if runes[i] == ')' {
+					if depth == 0 {
+						break
+					}
+					depth--
+				}
+			}
+			tokens = append(tokens, queryToken{text: string(runes[start:i])})
+		}
+	}
+
+	return tokens, nil
+}
+
+// queryParser is a recursive descent parser over query tokens
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	opts   Options
+	query  *Query
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for !p.done() && p.peek().isOperator("OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: not (["AND"] not)*
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for !p.done() {
+		token := p.peek()
+		if token.isOperator("AND") {
+			p.pos++
+		} else if token.isOperator("OR") || token.isOperator(")") {
+			break
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseNot parses: "NOT" not | near
+func (p *queryParser) parseNot() (queryNode, error) {
+	if !p.done() && p.peek().isOperator("NOT") {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseNear()
+}
+
+// parseNear parses: primary ("NEAR/n" primary)*
+func (p *queryParser) parseNear() (queryNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for !p.done() {
+		distance, ok := p.peek().nearDistance()
+		if !ok {
+			break
+		}
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = nearNode{left: left, right: right, distance: distance}
+	}
+	return left, nil
+}
+
+// parsePrimary parses: "(" or ")" | term
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	if p.done() {
+		return nil, fmt.Errorf("query ends where a term was expected")
+	}
+
+	token := p.peek()
+	p.pos++
+
+	if token.isOperator("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || !p.peek().isOperator(")") {
+			return nil, fmt.Errorf("missing ) in query")
+		}
+		p.pos++
+		return node, nil
+	}
+
+	if !token.quoted {
+		_, isNear := token.nearDistance()
+		if isNear || token.text == ")" || token.text == "AND" || token.text == "OR" || token.text == "NOT" {
+			return nil, fmt.Errorf("expected a term but found %q", token.text)
+		}
+	}
+
+	term, err := newTerm(token.text, len(p.query.terms), p.opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid term %q: %w", token.text, err)
+	}
+	p.query.terms = append(p.query.terms, term)
+	return term, nil
+}
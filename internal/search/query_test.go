@@ -0,0 +1,74 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchMultilineWholeWord(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		content   string
+		wantLines []int
+		wantSpans map[int]int
+	}{
+		{
+			name:      "match after a newline starts on its own line",
+			pattern:   "foo",
+			content:   "alpha\nfoo bar",
+			wantLines: []int{1},
+			wantSpans: map[int]int{1: 1},
+		},
+		{
+			name:      "matches sharing a boundary character",
+			pattern:   "foo",
+			content:   "foo\nfoo foo\nxfoo",
+			wantLines: []int{0, 1},
+			wantSpans: map[int]int{0: 0, 1: 1},
+		},
+		{
+			name:      "later match spanning lines",
+			pattern:   `foo\s+bar`,
+			content:   "x foo bar foo\nbar",
+			wantLines: []int{0},
+			wantSpans: map[int]int{0: 1},
+		},
+		{
+			name:      "pattern ending in punctuation",
+			pattern:   `foo\(`,
+			content:   "bar\nfoo(foo( y)",
+			wantLines: []int{1},
+			wantSpans: map[int]int{1: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := patternsQuery([]string{tt.pattern}, Options{WholeWord: true, Multiline: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			matches := query.matchMultiline(tt.content, strings.Count(tt.content, "\n")+1)
+			if got := matches.lines[0]; !reflect.DeepEqual(got, tt.wantLines) {
+				t.Errorf("lines = %v, want %v", got, tt.wantLines)
+			}
+			if !reflect.DeepEqual(matches.spans, tt.wantSpans) {
+				t.Errorf("spans = %v, want %v", matches.spans, tt.wantSpans)
+			}
+		})
+	}
+}
+
+func TestFindAllWholeWord(t *testing.T) {
+	query, err := patternsQuery([]string{"foo"}, Options{WholeWord: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := query.terms[0].findAll("foo foo,foo xfoo foox foo")
+	want := [][]int{{0, 3}, {4, 7}, {8, 11}, {22, 25}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findAll() = %v, want %v", got, want)
+	}
+}
@@ -3,8 +3,11 @@ package search
 import (
 	"bufio"
 	"bytes"
-	"os"
+	"context"
+	"fmt"
+	"io/fs"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -17,6 +20,29 @@ type Options struct {
 	ContextLines   int
 	EntireFunction bool
 	FuzzySearch    bool
+	FixedStrings   bool // Match patterns literally instead of as regular expressions
+	IgnoreCase     bool
+	WholeWord      bool // Only match where the pattern isn't part of a longer word
+
+	// Query is a boolean query (see ParseQuery) used instead of the patterns.
+	// With EntireFunction it is evaluated separately within each function.
+	Query string
+
+	// Multiline matches patterns against the whole file instead of line by
+	// line, so they can span lines. It can't be combined with FuzzySearch.
+	Multiline bool
+
+	// Top keeps only the N highest scoring snippets across all files (0 for all).
+	// Only fuzzy matches are scored.
+	Top int
+
+	// In restricts matches to these kinds of source text, such as comments
+	// or string literals (nil for all)
+	In []finder.SourceKind
+
+	Limits   finder.Limits    // Concurrency and memory bounds for reading files
+	Warnings *finder.Warnings // Collects files that couldn't be read; when nil the first error stops the search
+	Aliases  *finder.Aliases  // Other names of files reached through symlinks, shown with their results
 }
 
 // SearchResult represents a search match with context
@@ -28,7 +54,10 @@ type SearchResult struct {
 type SearchFile struct {
 	Path     string
 	Language string
+	Rev      string   // Git revision the file was read from, if not the working tree
+	Aliases  []string // Other paths the file was found under through symlinks
 	Snippets []CodeSnippet
+	Preamble Preamble // Package clause and imports, once set by AddPreambles
 }
 
 // CodeSnippet represents a matched code snippet
@@ -37,28 +66,152 @@ type CodeSnippet struct {
 	EndLine   int
 	Content   string
 	MatchInfo string
+	Patterns  []string // The search patterns that matched within the snippet
+	Score     int      // Fuzzy relevance of the best matching line; 0 outside fuzzy mode
+	Relation  string   // For functions added around the matches, how they relate, e.g. "called by Function Run"
+}
+
+// SearchInFiles searches for any of patterns in the given files of src
+func SearchInFiles(src finder.Source, files []string, patterns []string, opts Options) (SearchResult, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	return SearchStream(ctx, src, finder.SendPaths(ctx, files), patterns, opts)
 }
 
-// SearchInFiles searches for pattern in the given files
-func SearchInFiles(files []string, pattern string, opts Options) (SearchResult, error) {
+// SearchStream searches files concurrently as their paths arrive. A line
+// matches when any of patterns does. Results keep the order in which the
+// paths were received, except in fuzzy mode where files and snippets are
+// ranked by score.
+func SearchStream(ctx context.Context, src finder.Source, files <-chan string, patterns []string, opts Options) (SearchResult, error) {
 	result := SearchResult{}
+	if opts.Multiline && opts.FuzzySearch {
+		return result, fmt.Errorf("multiline search can't be combined with fuzzy matching")
+	}
 
-	for _, file := range files {
-		matches, err := searchInFile(file, pattern, opts)
-		if err != nil {
-			return result, err
-		}
+	var query *Query
+	var err error
+	if opts.Query != "" {
+		query, err = ParseQuery(opts.Query, opts)
+	} else {
+		query, err = patternsQuery(patterns, opts)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	matched, err := finder.Pipeline(ctx, src, files, opts.Limits, func(path string, _ fs.FileInfo) (SearchFile, error) {
+		return searchInFile(src, path, query, opts)
+	})
+	if err != nil {
+		return result, err
+	}
 
+	for _, matches := range matched {
 		if len(matches.Snippets) > 0 {
 			// Merge overlapping snippets before adding to result
 			matches.Snippets = mergeOverlappingSnippets(matches.Snippets)
+			matches.Aliases = opts.Aliases.For(matches.Path)
 			result.Files = append(result.Files, matches)
 		}
 	}
 
+	if opts.FuzzySearch {
+		rankFiles(result.Files)
+	}
+	if opts.Top > 0 {
+		result.Files = topSnippets(result.Files, opts.Top)
+	}
+
 	return result, nil
 }
 
+// rankFiles sorts the snippets of each file by score, and the files by their
+// best snippet. Ties keep their original order.
+func rankFiles(files []SearchFile) {
+	for i := range files {
+		sort.SliceStable(files[i].Snippets, func(a, b int) bool {
+			return files[i].Snippets[a].Score > files[i].Snippets[b].Score
+		})
+	}
+	sort.SliceStable(files, func(a, b int) bool {
+		return bestScore(files[a]) > bestScore(files[b])
+	})
+}
+
+// bestScore returns the highest snippet score of a file
+func bestScore(file SearchFile) int {
+	best := 0
+	for _, snippet := range file.Snippets {
+		best = max(best, snippet.Score)
+	}
+	return best
+}
+
+// topSnippets keeps the n highest scoring snippets across all files, in
+// their current order, dropping files left without any
+func topSnippets(files []SearchFile, n int) []SearchFile {
+	type ranked struct {
+		file, snippet, score int
+	}
+	var all []ranked
+	for i, file := range files {
+		for j, snippet := range file.Snippets {
+			all = append(all, ranked{file: i, snippet: j, score: snippet.Score})
+		}
+	}
+	if len(all) <= n {
+		return files
+	}
+
+	sort.SliceStable(all, func(a, b int) bool {
+		return all[a].score > all[b].score
+	})
+	keep := make(map[[2]int]bool, n)
+	for _, r := range all[:n] {
+		keep[[2]int{r.file, r.snippet}] = true
+	}
+
+	var kept []SearchFile
+	for i, file := range files {
+		var snippets []CodeSnippet
+		for j, snippet := range file.Snippets {
+			if keep[[2]int{i, j}] {
+				snippets = append(snippets, snippet)
+			}
+		}
+		if len(snippets) > 0 {
+			file.Snippets = snippets
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// compilePattern builds the regular expression for a single pattern
+func compilePattern(pattern string, opts Options) (*regexp.Regexp, error) {
+	return compileWithin(pattern, opts, `(?:^|\W)`)
+}
+
+// compileWithin builds the regular expression for a single pattern, starting
+// whole-word matches with leading. Like grep -w, a whole-word match must not
+// touch a word character on either side, which unlike \b also works for
+// patterns that start or end with punctuation. The characters around it are
+// part of the match, so the pattern itself is captured as the first group.
+func compileWithin(pattern string, opts Options, leading string) (*regexp.Regexp, error) {
+	expr := pattern
+	if opts.FixedStrings {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if opts.WholeWord {
+		expr = leading + `(` + expr + `)(?:\W|$)`
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
 // mergeOverlappingSnippets combines snippets that overlap or are adjacent
 func mergeOverlappingSnippets(snippets []CodeSnippet) []CodeSnippet {
 	if len(snippets) <= 1 {
@@ -76,10 +229,10 @@ func mergeOverlappingSnippets(snippets []CodeSnippet) []CodeSnippet {
 		current := snippets[i]
 		previous := &result[len(result)-1]
 
-		// Check if current snippet overlaps or is adjacent to the previous one
-		// Using a small buffer (e.g., 3 lines) to merge closely located snippets
-		const adjacencyBuffer = 3
-		if current.StartLine <= previous.EndLine+adjacencyBuffer {
+		// Check if current snippet overlaps or is adjacent to the previous one.
+		// Snippets further apart can't be joined, as the lines between them
+		// aren't in either.
+		if current.StartLine <= previous.EndLine+1 {
 			// Merge the snippets
 			if current.EndLine > previous.EndLine {
 				// Combine content by using the lines from the combined range
@@ -96,10 +249,14 @@ func mergeOverlappingSnippets(snippets []CodeSnippet) []CodeSnippet {
 				previous.EndLine = current.EndLine
 			}
 
+			previous.Score = max(previous.Score, current.Score)
+
 			// Update match info to reflect the merge
 			if previous.MatchInfo != current.MatchInfo {
+				previous.Patterns = mergePatterns(previous.Patterns, current.Patterns)
 				previous.MatchInfo = "Multiple matches between lines " +
-					strconv.Itoa(previous.StartLine) + "-" + strconv.Itoa(previous.EndLine)
+					strconv.Itoa(previous.StartLine) + "-" + strconv.Itoa(previous.EndLine) +
+					describePatterns(previous.Patterns)
 			}
 		} else {
 			// No overlap, add as a new snippet
@@ -110,29 +267,18 @@ func mergeOverlappingSnippets(snippets []CodeSnippet) []CodeSnippet {
 	return result
 }
 
-// searchInFile searches for pattern in a single file
-func searchInFile(path, pattern string, opts Options) (SearchFile, error) {
+// searchInFile runs the query over a single file
+func searchInFile(src finder.Source, path string, query *Query, opts Options) (SearchFile, error) {
 	fileObj := SearchFile{
-		Path:     path,
-		Language: finder.DetectLanguage(path),
-	}
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fileObj, err
+		Path: path,
+		Rev:  src.Rev,
 	}
 
-	var regex *regexp.Regexp
-	if opts.FuzzySearch {
-		// Create a fuzzy pattern
-		fuzzyPattern := strings.Join(strings.Split(pattern, ""), ".*")
-		regex, err = regexp.Compile("(?i)" + fuzzyPattern)
-	} else {
-		regex, err = regexp.Compile(pattern)
-	}
+	data, err := src.ReadFile(path)
 	if err != nil {
-		return fileObj, err
+		return fileObj, opts.Warnings.Record(path, "search", err)
 	}
+	fileObj.Language = finder.DetectLanguageContent(path, data)
 
 	scanner := bufio.NewScanner(bytes.NewReader(data))
 	var lines []string
@@ -140,73 +286,156 @@ func searchInFile(path, pattern string, opts Options) (SearchFile, error) {
 		lines = append(lines, scanner.Text())
 	}
 
-	for i, line := range lines {
-		if regex.MatchString(line) {
-			snippet := extractSnippet(lines, i, opts)
-			fileObj.Snippets = append(fileObj.Snippets, snippet)
-		}
+	// With In set, patterns are matched against a copy of the file in which
+	// every other kind of text is blanked out; snippets show the original
+	matchLines, content := lines, string(data)
+	if len(opts.In) > 0 {
+		matchLines = finder.MaskExcept(fileObj.Language, lines, opts.In...)
+		content = strings.Join(matchLines, "\n")
+	}
+
+	var matches termMatches
+	if opts.Multiline {
+		matches = query.matchMultiline(content, len(lines))
+	} else {
+		matches = query.match(matchLines)
+	}
+
+	// Function mode expands matches to the declarations around them
+	var blocks []finder.HeaderElement
+	if opts.EntireFunction {
+		blocks, _ = finder.ParseHeaders(path, data)
+	}
+
+	if opts.Query != "" && opts.EntireFunction {
+		fileObj.Snippets = functionSnippets(lines, blocks, query, matches)
+		return fileObj, nil
+	}
+
+	// Otherwise the query must hold for the file as a whole, and each line
+	// that contributed to it becomes a snippet
+	ok, hits := query.root.eval(matches, 0, len(lines)-1)
+	if !ok {
+		return fileObj, nil
+	}
+	for _, line := range hits.lines() {
+		snippet := extractSnippet(lines, blocks, line, matches.spanEnd(line), opts)
+		snippet.Patterns = hits[line]
+		snippet.Score = matches.scores[line]
+		snippet.MatchInfo += describePatterns(hits[line])
+		fileObj.Snippets = append(fileObj.Snippets, snippet)
 	}
 
 	return fileObj, nil
 }
 
-// extractSnippet extracts code around the match based on options
-func extractSnippet(lines []string, matchLine int, opts Options) CodeSnippet {
-	// If entire function mode is on, try to extract the function
+// functionSnippets evaluates the query separately within each function that
+// contains a line matching one of its terms, returning the functions for
+// which it holds. Lines outside any function or type are ignored.
+func functionSnippets(lines []string, blocks []finder.HeaderElement, query *Query, matches termMatches) []CodeSnippet {
+	var snippets []CodeSnippet
+	evaluated := make(map[[2]int]bool)
+
+	for _, line := range matches.candidates() {
+		start, end, block, ok := blockBounds(blocks, len(lines), line)
+		if !ok {
+			continue
+		}
+		if evaluated[[2]int{start, end}] {
+			continue
+		}
+		evaluated[[2]int{start, end}] = true
+
+		ok, hits := query.root.eval(matches, start, end)
+		if !ok {
+			continue
+		}
+		patterns := hits.patterns()
+		snippets = append(snippets, CodeSnippet{
+			StartLine: start + 1, // 1-indexed for display
+			EndLine:   end + 1,
+			Content:   strings.Join(lines[start:end+1], "\n"),
+			MatchInfo: block + " matching query at lines " + strconv.Itoa(start+1) + "-" + strconv.Itoa(end+1) + describePatterns(patterns),
+			Patterns:  patterns,
+			Score:     matches.best(hits.lines()),
+		})
+	}
+
+	return snippets
+}
+
+// describePatterns names the patterns that matched, for MatchInfo
+func describePatterns(patterns []string) string {
+	if len(patterns) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		quoted[i] = strconv.Quote(pattern)
+	}
+	return " for " + strings.Join(quoted, ", ")
+}
+
+// mergePatterns combines two pattern lists, keeping the first occurrence of each
+func mergePatterns(a, b []string) []string {
+	merged := append([]string(nil), a...)
+	for _, pattern := range b {
+		if !slices.Contains(merged, pattern) {
+			merged = append(merged, pattern)
+		}
+	}
+	return merged
+}
+
+// extractSnippet extracts code around a match spanning matchLine to
+// matchEnd based on options
+func extractSnippet(lines []string, blocks []finder.HeaderElement, matchLine, matchEnd int, opts Options) CodeSnippet {
+	location := "line " + strconv.Itoa(matchLine+1)
+	if matchEnd > matchLine {
+		location = "lines " + strconv.Itoa(matchLine+1) + "-" + strconv.Itoa(matchEnd+1)
+	}
+
+	// If entire function mode is on, try to extract the enclosing function
+	// or type
 	if opts.EntireFunction {
-		start, end := findFunctionBounds(lines, matchLine)
-		if start != -1 && end != -1 {
+		if start, end, block, ok := blockBounds(blocks, len(lines), matchLine); ok {
+			end = min(len(lines)-1, max(end, matchEnd))
 			return CodeSnippet{
 				StartLine: start + 1, // 1-indexed for display
 				EndLine:   end + 1,
 				Content:   strings.Join(lines[start:end+1], "\n"),
-				MatchInfo: "Function containing match at line " + strconv.Itoa(matchLine+1),
+				MatchInfo: block + " containing match at " + location,
 			}
 		}
 	}
 
 	// Fall back to context lines
 	start := max(0, matchLine-opts.ContextLines)
-	end := min(len(lines)-1, matchLine+opts.ContextLines)
+	end := min(len(lines)-1, matchEnd+opts.ContextLines)
 
 	return CodeSnippet{
 		StartLine: start + 1, // 1-indexed for display
 		EndLine:   end + 1,
 		Content:   strings.Join(lines[start:end+1], "\n"),
-		MatchInfo: "Match at line " + strconv.Itoa(matchLine+1),
+		MatchInfo: "Match at " + location,
 	}
 }
 
-// findFunctionBounds tries to determine function start/end around the match line
-func findFunctionBounds(lines []string, matchLine int) (start, end int) {
-	// Simple heuristic - look for opening/closing braces or indentation changes
-	// This would need to be language-specific for a robust implementation
-
-	// For now, this is a simplistic implementation that works with C-like languages
-	start = matchLine
-	for start > 0 {
-		if strings.Contains(lines[start], "func ") || strings.Contains(lines[start], "function ") {
-			break
-		}
-		start--
+// blockBounds returns the 0-based span of the innermost function, method
+// or type declared around matchLine, with a description such as
+// "Method Greeter.greet", and false when the line is outside all of them
+func blockBounds(blocks []finder.HeaderElement, lineCount, matchLine int) (start, end int, block string, ok bool) {
+	i := finder.EnclosingBlock(blocks, matchLine+1)
+	if i == -1 {
+		return 0, 0, "", false
 	}
 
-	bracketCount := 0
-	for i := start; i < len(lines); i++ {
-		line := lines[i]
-		bracketCount += strings.Count(line, "{") - strings.Count(line, "}")
-
-		if bracketCount <= 0 && i > matchLine {
-			end = i
-			break
-		}
+	header := blocks[i]
+	name := header.Name
+	if header.Parent != "" {
+		name = header.Parent + "." + name
 	}
-
-	if end == 0 {
-		end = min(len(lines)-1, start+20) // Fallback to 20 lines if we can't find the end
-	}
-
-	return start, end
+	return header.LineNum - 1, min(header.EndLine, lineCount) - 1, string(header.Type) + " " + name, true
 }
 
 func min(a, b int) int {
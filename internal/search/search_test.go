@@ -0,0 +1,57 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeOverlappingSnippets(t *testing.T) {
+	tests := []struct {
+		name     string
+		snippets []CodeSnippet
+		want     []CodeSnippet
+	}{
+		{
+			name: "overlapping",
+			snippets: []CodeSnippet{
+				{StartLine: 1, EndLine: 3, Content: "a\nb\nc", MatchInfo: "Match at line 2"},
+				{StartLine: 3, EndLine: 5, Content: "c\nd\ne", MatchInfo: "Match at line 2"},
+			},
+			want: []CodeSnippet{
+				{StartLine: 1, EndLine: 5, Content: "a\nb\nc\nd\ne", MatchInfo: "Match at line 2"},
+			},
+		},
+		{
+			name: "touching",
+			snippets: []CodeSnippet{
+				{StartLine: 4, EndLine: 5, Content: "d\ne", MatchInfo: "x"},
+				{StartLine: 1, EndLine: 3, Content: "a\nb\nc", MatchInfo: "x"},
+			},
+			want: []CodeSnippet{
+				{StartLine: 1, EndLine: 5, Content: "a\nb\nc\nd\ne", MatchInfo: "x"},
+			},
+		},
+		{
+			// The line between them is in neither, so joining them would
+			// label three lines of content as seven
+			name: "a line apart",
+			snippets: []CodeSnippet{
+				{StartLine: 1, EndLine: 3, Content: "a\nb\nc", MatchInfo: "x"},
+				{StartLine: 5, EndLine: 7, Content: "e\nf\ng", MatchInfo: "x"},
+			},
+			want: []CodeSnippet{
+				{StartLine: 1, EndLine: 3, Content: "a\nb\nc", MatchInfo: "x"},
+				{StartLine: 5, EndLine: 7, Content: "e\nf\ng", MatchInfo: "x"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeOverlappingSnippets(tt.snippets)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeOverlappingSnippets() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
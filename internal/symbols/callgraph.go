@@ -0,0 +1,457 @@
+package symbols
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/search"
+)
+
+// maxCallTargets bounds how many functions of the same name a call is
+// followed to when it can't be resolved more precisely. Calls to common
+// names such as Close or String would otherwise pull in half the tree.
+const maxCallTargets = 3
+
+// callSite matches a call in a line of code: an optional receiver and
+// member access operator, then the name and its opening parenthesis
+var callSite = regexp.MustCompile(`(?:([A-Za-z_$][A-Za-z0-9_$]*)\s*(\.|->|::)\s*)?([A-Za-z_$][A-Za-z0-9_$]*)\s*\(`)
+
+// callKeywords are words followed by "(" that aren't calls
+var callKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true,
+	"function": true, "def": true, "elif": true, "sizeof": true, "typeof": true, "func": true,
+	"foreach": true, "using": true, "lock": true, "fn": true, "match": true, "with": true,
+	"and": true, "or": true, "not": true, "in": true, "print": true, "super": true,
+}
+
+// selfNames are the receivers through which methods call their own class
+var selfNames = map[string]bool{"this": true, "self": true, "$this": true, "static": true}
+
+// call is a call made by a function, before it is resolved to the functions
+// it may reach
+type call struct {
+	from     int    // Index of the calling declaration in its file
+	name     string // Name of the function or method called
+	receiver string // What the call is made through, e.g. a package or variable; empty for a bare call
+	self     bool   // Made through the caller's own receiver, this or self
+	pkgPath  string // Import path, when made through an imported Go package
+
+	// For Go, the named type of the value a method is called on when the
+	// syntax tree tells, and the fields of it the call goes through, as
+	// repo and nil for r.Close() or Searcher and [repo] for s.repo.Close()
+	typ    typeRef
+	fields []string
+}
+
+// funcNode is a function or method in the call graph
+type funcNode struct {
+	file *fileIndex
+	decl int
+}
+
+// goTypeKey identifies a Go type by its package's import path and its name
+type goTypeKey struct {
+	pkg  string
+	name string
+}
+
+// CallGraph holds the functions of a tree and the static calls between them.
+// Go calls come from the syntax tree; other languages are matched by name.
+type CallGraph struct {
+	files   map[string]*fileIndex
+	funcs   []funcNode
+	ids     map[funcNode]int
+	byName  map[string][]int
+	callees map[int][]int
+	callers map[int][]int
+
+	pkgs   goPackages
+	fields map[goTypeKey][]goField // Fields of the Go struct types
+}
+
+// BuildCallGraph reads files as their paths arrive and links every function
+// to the functions it calls
+func BuildCallGraph(ctx context.Context, src finder.Source, files <-chan string, opts Options) (*CallGraph, error) {
+	type parsedFile struct {
+		file   *fileIndex
+		calls  []call
+		fields map[string][]goField
+	}
+
+	parsed, err := finder.Pipeline(ctx, src, files, opts.Limits, func(path string, _ fs.FileInfo) (parsedFile, error) {
+		data, err := src.ReadFile(path)
+		if err != nil {
+			return parsedFile{}, opts.Warnings.Record(path, "read", err)
+		}
+
+		file := indexFile(src, path, data)
+		if dataLanguages[file.language] {
+			return parsedFile{}, nil
+		}
+		if file.language == "go" {
+			if parsed, ok := goCalls(file, data); ok {
+				return parsedFile{file: file, calls: parsed.calls, fields: parsed.fields}, nil
+			}
+		}
+		return parsedFile{file: file, calls: file.lexicalCalls()}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	g := &CallGraph{
+		files:   make(map[string]*fileIndex),
+		ids:     make(map[funcNode]int),
+		byName:  make(map[string][]int),
+		callees: make(map[int][]int),
+		callers: make(map[int][]int),
+		pkgs:    newGoPackages(src),
+		fields:  make(map[goTypeKey][]goField),
+	}
+	for _, p := range parsed {
+		if p.file == nil {
+			continue
+		}
+		g.files[p.file.path] = p.file
+		for name, fields := range p.fields {
+			g.fields[goTypeKey{pkg: g.pkgs.of(p.file.path), name: name}] = fields
+		}
+		for i, decl := range p.file.decls {
+			if isFunction(decl) {
+				node := funcNode{file: p.file, decl: i}
+				g.ids[node] = len(g.funcs)
+				g.byName[decl.Name] = append(g.byName[decl.Name], len(g.funcs))
+				g.funcs = append(g.funcs, node)
+			}
+		}
+	}
+
+	for _, p := range parsed {
+		for _, c := range p.calls {
+			from, ok := g.ids[funcNode{file: p.file, decl: c.from}]
+			if !ok {
+				continue
+			}
+			for _, to := range g.resolve(g.funcs[from], c) {
+				if to != from && !slices.Contains(g.callees[from], to) {
+					g.callees[from] = append(g.callees[from], to)
+					g.callers[to] = append(g.callers[to], from)
+				}
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// isFunction reports whether a declaration is a function or method with a body
+func isFunction(decl finder.HeaderElement) bool {
+	return (decl.Type == finder.Function || decl.Type == finder.Method) && decl.EndLine >= decl.LineNum
+}
+
+// lexicalCalls finds calls by looking for names followed by "(" in the code
+// of each function, attributing them to the innermost function around them
+func (f *fileIndex) lexicalCalls() []call {
+	code := finder.MaskExcept(f.language, f.lines, finder.KindCode)
+
+	var calls []call
+	for i, line := range code {
+		from := f.functionAt(i + 1)
+		if from == -1 {
+			continue
+		}
+		for _, m := range callSite.FindAllStringSubmatch(line, -1) {
+			receiver, name := m[1], m[3]
+			if callKeywords[name] || (i+1 == f.decls[from].LineNum && name == f.decls[from].Name && receiver == "") {
+				continue // Keywords, and the function's own declaration
+			}
+			calls = append(calls, call{
+				from:     from,
+				name:     name,
+				receiver: receiver,
+				self:     selfNames[receiver],
+			})
+		}
+	}
+	return calls
+}
+
+// functionAt returns the index of the innermost function around line, or -1
+func (f *fileIndex) functionAt(line int) int {
+	best := -1
+	for i, decl := range f.decls {
+		if !isFunction(decl) || line < decl.LineNum || line > decl.EndLine {
+			continue
+		}
+		if best == -1 || decl.LineNum >= f.decls[best].LineNum {
+			best = i
+		}
+	}
+	return best
+}
+
+// resolve returns the functions a call made by caller may reach
+func (g *CallGraph) resolve(caller funcNode, c call) []int {
+	from := caller.file.decls[caller.decl]
+	fromDir := path.Dir(caller.file.path)
+
+	var candidates []int
+	for _, id := range g.byName[c.name] {
+		if g.funcs[id].file.language == caller.file.language {
+			candidates = append(candidates, id)
+		}
+	}
+
+	keep := func(match func(node funcNode, decl finder.HeaderElement) bool) []int {
+		var kept []int
+		for _, id := range candidates {
+			node := g.funcs[id]
+			if match(node, node.file.decls[node.decl]) {
+				kept = append(kept, id)
+			}
+		}
+		return kept
+	}
+
+	if c.typ.name != "" {
+		if methods, known := g.typedCall(caller.file, c, candidates); known {
+			return methods
+		}
+	}
+
+	switch {
+	case c.pkgPath != "":
+		// A function of an imported package of the module
+		return keep(func(node funcNode, decl finder.HeaderElement) bool {
+			return decl.Parent == "" && g.pkgs.of(node.file.path) == c.pkgPath
+		})
+
+	case c.self:
+		return keep(func(node funcNode, decl finder.HeaderElement) bool {
+			return decl.Parent == from.Parent
+		})
+
+	case c.receiver != "":
+		// A method called on some value, whose type is unknown
+		methods := keep(func(node funcNode, decl finder.HeaderElement) bool {
+			return decl.Parent != ""
+		})
+		if len(methods) > maxCallTargets {
+			return nil
+		}
+		return methods
+
+	default:
+		// A bare call reaches the closest function of that name: in the same
+		// class, file or directory, or for Go the same package only
+		isGo := caller.file.language == "go"
+		for _, scope := range []func(node funcNode, decl finder.HeaderElement) bool{
+			func(node funcNode, decl finder.HeaderElement) bool {
+				return !isGo && node.file == caller.file && from.Parent != "" && decl.Parent == from.Parent
+			},
+			func(node funcNode, decl finder.HeaderElement) bool {
+				return node.file == caller.file && decl.Parent == ""
+			},
+			func(node funcNode, decl finder.HeaderElement) bool {
+				return path.Dir(node.file.path) == fromDir && decl.Parent == ""
+			},
+		} {
+			if found := keep(scope); len(found) > 0 {
+				return found
+			}
+		}
+		if isGo || len(candidates) > maxCallTargets {
+			return nil
+		}
+		return candidates
+	}
+}
+
+// typedCall resolves a Go method call on a value of known type to the
+// methods of that type, or of the types it embeds. known is false when the
+// type isn't a struct of the tree, such as an interface, so the call has to
+// be matched by name instead.
+func (g *CallGraph) typedCall(caller *fileIndex, c call, candidates []int) (methods []int, known bool) {
+	t, ok := g.typeKey(g.pkgs.of(caller.path), c.typ)
+	if !ok {
+		return nil, true // A type from outside the module
+	}
+	for _, name := range c.fields {
+		field, owner, found := g.field(t, name, 0)
+		if !found || field.typ.name == "" {
+			return nil, false
+		}
+		if t, ok = g.typeKey(owner.pkg, field.typ); !ok {
+			return nil, true
+		}
+	}
+	return g.methods(t, c.name, candidates, 0)
+}
+
+// maxEmbedding bounds how deep embedded fields are followed for promoted
+// fields and methods
+const maxEmbedding = 3
+
+// typeKey returns the key of a type referred to from package pkg, and false
+// when it comes from outside the module
+func (g *CallGraph) typeKey(pkg string, t typeRef) (goTypeKey, bool) {
+	if t.pkgPath == "" {
+		return goTypeKey{pkg: pkg, name: t.name}, true
+	}
+	return goTypeKey{pkg: t.pkgPath, name: t.name}, g.pkgs.module.Contains(t.pkgPath)
+}
+
+// field finds a field of a struct type by name, including fields promoted
+// from embedded structs, and returns it with the struct declaring it
+func (g *CallGraph) field(t goTypeKey, name string, depth int) (goField, goTypeKey, bool) {
+	for _, field := range g.fields[t] {
+		if field.name == name {
+			return field, t, true
+		}
+	}
+	if depth >= maxEmbedding {
+		return goField{}, goTypeKey{}, false
+	}
+	for _, field := range g.fields[t] {
+		if embedded, ok := g.typeKey(t.pkg, field.typ); ok && field.embedded {
+			if found, owner, ok := g.field(embedded, name, depth+1); ok {
+				return found, owner, true
+			}
+		}
+	}
+	return goField{}, goTypeKey{}, false
+}
+
+// methods returns the candidates that are methods of a type, or of the
+// types it embeds when it has none of that name itself
+func (g *CallGraph) methods(t goTypeKey, name string, candidates []int, depth int) ([]int, bool) {
+	var found []int
+	for _, id := range candidates {
+		node := g.funcs[id]
+		if node.file.decls[node.decl].Parent == t.name && g.pkgs.of(node.file.path) == t.pkg {
+			found = append(found, id)
+		}
+	}
+	if len(found) > 0 {
+		return found, true
+	}
+
+	fields, isStruct := g.fields[t]
+	if !isStruct {
+		return nil, false
+	}
+	if depth < maxEmbedding {
+		for _, field := range fields {
+			if embedded, ok := g.typeKey(t.pkg, field.typ); ok && field.embedded {
+				if promoted, _ := g.methods(embedded, name, candidates, depth+1); len(promoted) > 0 {
+					return promoted, true
+				}
+			}
+		}
+	}
+	return nil, true
+}
+
+// seeds returns the functions a snippet of path covers: the one around its
+// first line and any that start within it
+func (g *CallGraph) seeds(filePath string, start, end int) []int {
+	file, ok := g.files[filePath]
+	if !ok {
+		return nil
+	}
+
+	var ids []int
+	if i := file.functionAt(start); i != -1 {
+		ids = append(ids, g.ids[funcNode{file: file, decl: i}])
+	}
+	for i, decl := range file.decls {
+		if isFunction(decl) && decl.LineNum > start && decl.LineNum <= end {
+			ids = append(ids, g.ids[funcNode{file: file, decl: i}])
+		}
+	}
+	return ids
+}
+
+// Expand adds to results the functions called by the functions in it, up to
+// callees calls deep, and the functions calling them, up to callers calls
+// deep. Each function appears once, and functions already in results aren't
+// added again.
+func (g *CallGraph) Expand(results search.SearchResult, callees, callers int) search.SearchResult {
+	// Start from every function the results already cover
+	seen := make(map[int]bool)
+	var seeds []int
+	for _, file := range results.Files {
+		for _, snippet := range file.Snippets {
+			for _, id := range g.seeds(file.Path, snippet.StartLine, snippet.EndLine) {
+				if !seen[id] {
+					seen[id] = true
+					seeds = append(seeds, id)
+				}
+			}
+		}
+	}
+
+	added := make(map[int]string)
+	var order []int
+	walk := func(edges map[int][]int, depth int, relation string) {
+		frontier := seeds
+		for level := 0; level < depth && len(frontier) > 0; level++ {
+			var next []int
+			for _, id := range frontier {
+				for _, to := range edges[id] {
+					if seen[to] {
+						continue
+					}
+					seen[to] = true
+					added[to] = relation + " " + g.describe(id)
+					order = append(order, to)
+					next = append(next, to)
+				}
+			}
+			frontier = next
+		}
+	}
+	walk(g.callees, callees, "called by")
+	walk(g.callers, callers, "calls")
+
+	for _, id := range order {
+		node := g.funcs[id]
+		def := node.file.definition(node.decl)
+		snippet := search.CodeSnippet{
+			StartLine: def.StartLine,
+			EndLine:   def.EndLine,
+			Content:   def.Content,
+			MatchInfo: "Function " + def.QualifiedName(),
+			Relation:  added[id],
+		}
+		results.Files = appendSnippet(results.Files, node.file, snippet)
+	}
+	return results
+}
+
+// describe names a function of the graph, e.g. "Method Order.Total"
+func (g *CallGraph) describe(id int) string {
+	node := g.funcs[id]
+	return describe(node.file.decls[node.decl])
+}
+
+// appendSnippet adds a snippet to the results of its file, adding the file
+// when it has no results yet
+func appendSnippet(files []search.SearchFile, file *fileIndex, snippet search.CodeSnippet) []search.SearchFile {
+	for i := range files {
+		if files[i].Path == file.path {
+			files[i].Snippets = append(files[i].Snippets, snippet)
+			return files
+		}
+	}
+	return append(files, search.SearchFile{
+		Path:     file.path,
+		Language: file.language,
+		Rev:      file.rev,
+		Snippets: []search.CodeSnippet{snippet},
+	})
+}
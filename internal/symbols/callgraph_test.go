@@ -0,0 +1,132 @@
+package symbols
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// goModuleTree is a module with two packages of the same name and methods
+// of the same name on several types
+var goModuleTree = fstest.MapFS{
+	"go.mod": {Data: []byte("module example.com/gm\n\ngo 1.22\n")},
+	"a/util/u.go": {Data: []byte(`package util
+
+func Helper() int { return 1 }
+`)},
+	"b/util/u.go": {Data: []byte(`package util
+
+func Helper() int { return 2 }
+`)},
+	"c/v2/c.go": {Data: []byte(`package c
+
+func Run() {}
+`)},
+	"svc/store.go": {Data: []byte(`package svc
+
+type Store struct{}
+
+func (s *Store) Close() error { return nil }
+
+type Other struct{}
+
+func (o Other) Close() error { return nil }
+
+type Base struct{}
+
+func (b *Base) Flush() {}
+
+type Unused struct{}
+
+func (u *Unused) Flush() {}
+`)},
+	"svc/service.go": {Data: []byte(`package svc
+
+import (
+	"strings"
+
+	"example.com/gm/a/util"
+	"example.com/gm/c/v2"
+)
+
+type Service struct {
+	Base
+	store *Store
+}
+
+func (s *Service) Run(b strings.Builder) int {
+	s.store.Close()
+	s.Flush()
+	o := Other{}
+	o.Close()
+	b.Reset()
+	c.Run()
+	return util.Helper()
+}
+`)},
+}
+
+func TestCallGraphGo(t *testing.T) {
+	for _, root := range []string{"", "/abs/checkout"} {
+		src := finder.FSSource(goModuleTree, root)
+		paths, err := finder.SelectFiles(src, nil, finder.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := BuildCallGraph(context.Background(), src, finder.SendPaths(context.Background(), paths), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, id := range g.callees[g.find(t, "Service", "Run")] {
+			node := g.funcs[id]
+			got = append(got, g.pkgs.of(node.file.path)+": "+g.describe(id))
+		}
+		sort.Strings(got)
+		want := []string{
+			"example.com/gm/a/util: Function Helper",
+			"example.com/gm/c/v2: Function Run",
+			"example.com/gm/svc: Method Base.Flush",
+			"example.com/gm/svc: Method Other.Close",
+			"example.com/gm/svc: Method Store.Close",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("root %q: callees of Service.Run = %q, want %q", root, got, want)
+		}
+	}
+}
+
+// find returns the id of the function with the given parent and name
+func (g *CallGraph) find(t *testing.T, parent, name string) int {
+	t.Helper()
+	for id, node := range g.funcs {
+		decl := node.file.decls[node.decl]
+		if decl.Parent == parent && decl.Name == name {
+			return id
+		}
+	}
+	t.Fatalf("no function %s.%s", parent, name)
+	return -1
+}
+
+func TestPackageName(t *testing.T) {
+	tests := map[string]string{
+		"fmt":                       "fmt",
+		"net/http":                  "http",
+		"example.com/mod/v2":        "mod",
+		"example.com/mod/v2/client": "client",
+		"gopkg.in/yaml.v3":          "yaml",
+		"github.com/x/v2ray":        "v2ray",
+		"v2":                        "v2",
+	}
+	for importPath, want := range tests {
+		if got := packageName(importPath); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", importPath, got, want)
+		}
+	}
+}
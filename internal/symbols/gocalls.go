@@ -0,0 +1,296 @@
+package symbols
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"regexp"
+	"strconv"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// goPackages names the packages of the Go files in a source by their import
+// paths, using the go.mod that governs the source
+type goPackages struct {
+	src    finder.Source
+	module finder.GoModule
+}
+
+// newGoPackages finds the module of a source. Without one, packages are
+// named by their directory and imports never resolve to them.
+func newGoPackages(src finder.Source) goPackages {
+	module, _ := finder.FindGoModule(src)
+	return goPackages{src: src, module: module}
+}
+
+// of returns the import path of the package a file is in
+func (p goPackages) of(filePath string) string {
+	name, ok := p.src.Name(filePath)
+	if !ok {
+		return path.Dir(filePath)
+	}
+	return p.module.ImportPath(path.Dir(name))
+}
+
+// goField is a field of a Go struct type
+type goField struct {
+	name     string
+	typ      typeRef // Empty when the field's type isn't a named type
+	embedded bool
+}
+
+// goFile is what the call graph learns from the syntax tree of a Go file
+type goFile struct {
+	calls  []call
+	fields map[string][]goField // Fields of the struct types declared in the file, by type name
+}
+
+// goCalls finds the calls made by each function of a Go file from its syntax
+// tree, telling package functions apart from methods. Methods called on a
+// receiver, parameter, typed variable or one of their fields are tied to the
+// type of the value. It reports false when the file doesn't parse.
+func goCalls(file *fileIndex, data []byte) (goFile, bool) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file.path, data, parser.SkipObjectResolution)
+	if err != nil {
+		return goFile{}, false
+	}
+
+	imports := goImports(parsed)
+	result := goFile{fields: goStructFields(parsed, imports)}
+
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		from := file.declAt(fn.Name.Name, fset.Position(fn.Pos()).Line)
+		if from == -1 {
+			continue
+		}
+
+		vars := goVarTypes(fn, imports)
+		ast.Inspect(fn.Body, func(node ast.Node) bool {
+			expr, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			// Explicit instantiations such as Map[int](xs) call Map
+			fun := expr.Fun
+			switch f := fun.(type) {
+			case *ast.IndexExpr:
+				fun = f.X
+			case *ast.IndexListExpr:
+				fun = f.X
+			}
+
+			switch f := fun.(type) {
+			case *ast.Ident:
+				result.calls = append(result.calls, call{from: from, name: f.Name})
+			case *ast.SelectorExpr:
+				c := call{from: from, name: f.Sel.Name, receiver: types.ExprString(f.X)}
+				if root, fields := selectorChain(f.X); root != "" {
+					if t, ok := vars[root]; ok {
+						c.typ, c.fields = t, fields
+					} else if len(fields) == 0 {
+						c.pkgPath = imports[root]
+					}
+				}
+				result.calls = append(result.calls, c)
+			}
+			return true
+		})
+	}
+	return result, true
+}
+
+// goVarTypes returns the named types of a function's receiver, parameters
+// and results, and of the variables its body declares with a type or
+// initializes with a composite literal or new. Scopes aren't told apart.
+func goVarTypes(fn *ast.FuncDecl, imports map[string]string) map[string]typeRef {
+	vars := make(map[string]typeRef)
+	set := func(name *ast.Ident, t typeRef, ok bool) {
+		if ok && name.Name != "_" {
+			vars[name.Name] = t
+		}
+	}
+	for _, list := range []*ast.FieldList{fn.Recv, fn.Type.Params, fn.Type.Results} {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			t, ok := namedType(field.Type, imports)
+			for _, name := range field.Names {
+				set(name, t, ok)
+			}
+		}
+	}
+
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if n.Type != nil {
+					t, ok := namedType(n.Type, imports)
+					set(name, t, ok)
+				} else if i < len(n.Values) {
+					t, ok := valueType(n.Values[i], imports)
+					set(name, t, ok)
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE || len(n.Lhs) != len(n.Rhs) {
+				break
+			}
+			for i, lhs := range n.Lhs {
+				if name, ok := lhs.(*ast.Ident); ok {
+					t, ok := valueType(n.Rhs[i], imports)
+					set(name, t, ok)
+				}
+			}
+		}
+		return true
+	})
+	return vars
+}
+
+// goStructFields returns the fields of the struct types a Go file declares
+func goStructFields(file *ast.File, imports map[string]string) map[string][]goField {
+	fields := make(map[string][]goField)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.TypeSpec)
+			st, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				t, _ := namedType(field.Type, imports)
+				if len(field.Names) == 0 {
+					fields[spec.Name.Name] = append(fields[spec.Name.Name], goField{name: t.name, typ: t, embedded: true})
+				}
+				for _, name := range field.Names {
+					fields[spec.Name.Name] = append(fields[spec.Name.Name], goField{name: name.Name, typ: t})
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// namedType returns the named type of a type expression, looking through
+// pointers and type arguments, such as finder.Source for *finder.Source
+func namedType(expr ast.Expr, imports map[string]string) (typeRef, bool) {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return typeRef{name: e.Name}, true
+		case *ast.SelectorExpr:
+			x, ok := e.X.(*ast.Ident)
+			if !ok || imports[x.Name] == "" {
+				return typeRef{}, false
+			}
+			return typeRef{pkgPath: imports[x.Name], name: e.Sel.Name}, true
+		default:
+			return typeRef{}, false
+		}
+	}
+}
+
+// valueType returns the named type of a value whose type shows in the
+// expression itself: T{}, &T{} or new(T)
+func valueType(expr ast.Expr, imports map[string]string) (typeRef, bool) {
+	switch e := expr.(type) {
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return valueType(e.X, imports)
+		}
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			return namedType(e.Type, imports)
+		}
+	case *ast.CallExpr:
+		if fun, ok := e.Fun.(*ast.Ident); ok && fun.Name == "new" && len(e.Args) == 1 {
+			return namedType(e.Args[0], imports)
+		}
+	}
+	return typeRef{}, false
+}
+
+// selectorChain splits x.a.b into the name x and the fields a and b. The
+// name is empty when the expression doesn't start with one.
+func selectorChain(expr ast.Expr) (string, []string) {
+	var fields []string
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return e.Name, fields
+		case *ast.SelectorExpr:
+			fields = append([]string{e.Sel.Name}, fields...)
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		default:
+			return "", nil
+		}
+	}
+}
+
+// goImports maps the names a Go file's imports are referred to by to their paths
+func goImports(file *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := packageName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+	return imports
+}
+
+// majorVersion matches the major version suffix of a module path, as in
+// example.com/mod/v2, or of a gopkg.in path, as in gopkg.in/yaml.v3
+var majorVersion = regexp.MustCompile(`(?:^|[/.])v[0-9]+$`)
+
+// packageName returns the name an import without an alias is referred to by:
+// the last element of its path, passing over a major version suffix
+func packageName(importPath string) string {
+	trimmed := importPath
+	if loc := majorVersion.FindStringIndex(importPath); loc != nil && loc[0] > 0 {
+		trimmed = importPath[:loc[0]]
+	}
+	return path.Base(trimmed)
+}
+
+// declAt returns the index of the declaration of name at line, or -1
+func (f *fileIndex) declAt(name string, line int) int {
+	for i, decl := range f.decls {
+		if decl.Name == name && decl.LineNum == line {
+			return i
+		}
+	}
+	return -1
+}
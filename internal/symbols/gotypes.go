@@ -0,0 +1,268 @@
+package symbols
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/search"
+)
+
+// typeRef is a type name used in Go code
+type typeRef struct {
+	pkgPath string // Import path when qualified by an imported package; empty within the package
+	name    string
+}
+
+// goDecl is a top-level Go declaration and the names it uses that may be types
+type goDecl struct {
+	file      *fileIndex
+	pkg       string // Name of the package the file declares
+	decl      int    // Index of the declaration in the file's decls
+	startLine int
+	endLine   int
+	refs      []typeRef
+}
+
+// TypeIndex holds the type declarations of the Go packages in a tree, and the
+// type names each top-level declaration uses
+type TypeIndex struct {
+	decls map[string][]*goDecl          // By file path
+	types map[string]map[string]*goDecl // By package key, then type name
+	pkgs  goPackages
+}
+
+// pkgKey returns the key of the package a declaration belongs to: its import
+// path, with "_test" added for the external test package of a directory so
+// its types don't replace those of the package it tests
+func (x *TypeIndex) pkgKey(d *goDecl) string {
+	key := x.pkgs.of(d.file.path)
+	if strings.HasSuffix(d.pkg, "_test") {
+		key += "_test"
+	}
+	return key
+}
+
+// BuildTypeIndex reads the Go files among files as their paths arrive and
+// records which type names each declaration refers to
+func BuildTypeIndex(ctx context.Context, src finder.Source, files <-chan string, opts Options) (*TypeIndex, error) {
+	parsed, err := finder.Pipeline(ctx, src, files, opts.Limits, func(filePath string, _ fs.FileInfo) ([]*goDecl, error) {
+		if path.Ext(filePath) != ".go" {
+			return nil, nil
+		}
+		data, err := src.ReadFile(filePath)
+		if err != nil {
+			return nil, opts.Warnings.Record(filePath, "read", err)
+		}
+		return goDecls(indexFile(src, filePath, data), data), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index := &TypeIndex{
+		decls: make(map[string][]*goDecl),
+		types: make(map[string]map[string]*goDecl),
+		pkgs:  newGoPackages(src),
+	}
+	for _, decls := range parsed {
+		for _, d := range decls {
+			index.decls[d.file.path] = append(index.decls[d.file.path], d)
+
+			decl := d.file.decls[d.decl]
+			if decl.Type != finder.Struct && decl.Type != finder.Interface && decl.Type != finder.NamedType {
+				continue
+			}
+			pkg := index.pkgKey(d)
+			if index.types[pkg] == nil {
+				index.types[pkg] = make(map[string]*goDecl)
+			}
+			index.types[pkg][decl.Name] = d
+		}
+	}
+	return index, nil
+}
+
+// goDecls finds the type names used by each top-level declaration of a Go
+// file. Files that don't parse have none.
+func goDecls(file *fileIndex, data []byte) []*goDecl {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file.path, data, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	imports := goImports(parsed)
+
+	var decls []*goDecl
+	add := func(name string, node ast.Node, uses ...ast.Node) {
+		line := fset.Position(node.Pos()).Line
+		i := file.declAt(name, line)
+		if i == -1 {
+			return
+		}
+		d := &goDecl{
+			file:      file,
+			pkg:       parsed.Name.Name,
+			decl:      i,
+			startLine: line,
+			endLine:   fset.Position(node.End()).Line,
+		}
+		for _, use := range uses {
+			d.refs = append(d.refs, typeRefs(use, imports)...)
+		}
+		decls = append(decls, d)
+	}
+
+	for _, decl := range parsed.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			uses := []ast.Node{decl.Type}
+			if decl.Recv != nil {
+				uses = append(uses, decl.Recv)
+			}
+			if decl.Body != nil {
+				uses = append(uses, decl.Body)
+			}
+			add(decl.Name.Name, decl, uses...)
+
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					uses := []ast.Node{spec.Type}
+					if spec.TypeParams != nil {
+						uses = append(uses, spec.TypeParams)
+					}
+					add(spec.Name.Name, spec, uses...)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						var uses []ast.Node
+						if spec.Type != nil {
+							uses = append(uses, spec.Type)
+						}
+						for _, value := range spec.Values {
+							uses = append(uses, value)
+						}
+						add(name.Name, name, uses...)
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// typeRefs collects the names in node that may refer to types: identifiers,
+// and names qualified by an imported package. Field names, member accesses
+// and composite literal keys are skipped.
+func typeRefs(node ast.Node, imports map[string]string) []typeRef {
+	var refs []typeRef
+	seen := make(map[typeRef]bool)
+	addRef := func(ref typeRef) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			addRef(typeRef{name: n.Name})
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && imports[x.Name] != "" {
+				addRef(typeRef{pkgPath: imports[x.Name], name: n.Sel.Name})
+				return false
+			}
+			ast.Inspect(n.X, visit)
+			return false
+		case *ast.Field:
+			ast.Inspect(n.Type, visit)
+			return false
+		case *ast.KeyValueExpr:
+			if _, ok := n.Key.(*ast.Ident); !ok {
+				ast.Inspect(n.Key, visit)
+			}
+			ast.Inspect(n.Value, visit)
+			return false
+		}
+		return true
+	}
+	ast.Inspect(node, visit)
+	return refs
+}
+
+// lookup returns the type a name used in a file of the package keyed pkg
+// refers to, if it is declared in the tree. Imported packages must belong to
+// the module.
+func (x *TypeIndex) lookup(pkg string, ref typeRef) *goDecl {
+	if ref.pkgPath == "" {
+		return x.types[pkg][ref.name]
+	}
+	if !x.pkgs.module.Contains(ref.pkgPath) {
+		return nil
+	}
+	return x.types[ref.pkgPath][ref.name]
+}
+
+// Expand adds to results the definitions of the Go types the clipped code
+// uses, and of the types those use in turn, up to depth levels from the
+// clipped code (0 for no limit), so the clip reads on its own. Types declared
+// in the same package or in imported packages of the tree are followed;
+// types already in results aren't added again.
+func (x *TypeIndex) Expand(results search.SearchResult, depth int) search.SearchResult {
+	type pending struct {
+		decl  *goDecl
+		user  *goDecl
+		level int // Levels of types between the clipped code and this one
+	}
+
+	// Start from every declaration the results cover
+	seen := make(map[*goDecl]bool)
+	var queue []pending
+	for _, file := range results.Files {
+		for _, snippet := range file.Snippets {
+			for _, d := range x.decls[file.Path] {
+				if d.startLine <= snippet.EndLine && d.endLine >= snippet.StartLine && !seen[d] {
+					seen[d] = true
+					queue = append(queue, pending{decl: d})
+				}
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		if next.user != nil {
+			def := next.decl.file.definition(next.decl.decl)
+			results.Files = appendSnippet(results.Files, next.decl.file, search.CodeSnippet{
+				StartLine: def.StartLine,
+				EndLine:   def.EndLine,
+				Content:   def.Content,
+				MatchInfo: string(def.Type) + " " + def.QualifiedName(),
+				Relation:  "used by " + describe(next.user.file.decls[next.user.decl]),
+			})
+		}
+
+		if depth > 0 && next.level >= depth {
+			continue
+		}
+		pkg := x.pkgKey(next.decl)
+		for _, ref := range next.decl.refs {
+			if d := x.lookup(pkg, ref); d != nil && !seen[d] {
+				seen[d] = true
+				queue = append(queue, pending{decl: d, user: next.decl, level: next.level + 1})
+			}
+		}
+	}
+	return results
+}
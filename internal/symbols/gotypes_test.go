@@ -0,0 +1,101 @@
+package symbols
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/search"
+)
+
+// goTypesTree is a module whose packages declare types of the same name
+var goTypesTree = fstest.MapFS{
+	"go.mod": {Data: []byte("module example.com/gt\n\ngo 1.22\n")},
+	"internal/model/model.go": {Data: []byte(`package model
+
+type Order struct {
+	Items []Item
+}
+
+type Item struct {
+	Name  string
+	Price Price
+}
+
+type Price struct{ Cents int }
+`)},
+	"internal/model/model_test.go": {Data: []byte(`package model_test
+
+type Order struct{ Fake bool }
+`)},
+	"internal/other/model/model.go": {Data: []byte(`package model
+
+type Order struct{ ID int }
+`)},
+	"app/handler.go": {Data: []byte(`package app
+
+import "example.com/gt/internal/model"
+
+func Handle(o model.Order) int {
+	return len(o.Items)
+}
+`)},
+}
+
+func TestTypeIndexExpand(t *testing.T) {
+	model := "internal/model/model.go"
+	all := []string{
+		model + ": Struct Order, used by Function Handle",
+		model + ": Struct Item, used by Struct Order",
+		model + ": Struct Price, used by Struct Item",
+	}
+	tests := []struct {
+		root  string
+		depth int
+		want  []string
+	}{
+		{root: "", depth: 0, want: all},
+		{root: "/abs/checkout", depth: 0, want: all},
+		{root: "", depth: 1, want: all[:1]},
+		{root: "", depth: 2, want: all[:2]},
+		{root: "", depth: 3, want: all},
+	}
+
+	for _, tt := range tests {
+		root := tt.root
+		src := finder.FSSource(goTypesTree, root)
+		paths, err := finder.SelectFiles(src, nil, finder.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		index, err := BuildTypeIndex(context.Background(), src, finder.SendPaths(context.Background(), paths), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results := search.SearchResult{Files: []search.SearchFile{{
+			Path:     src.Path("app/handler.go"),
+			Snippets: []search.CodeSnippet{{StartLine: 5, EndLine: 7}},
+		}}}
+		results = index.Expand(results, tt.depth)
+
+		var got []string
+		for _, file := range results.Files {
+			for _, snippet := range file.Snippets {
+				if snippet.Relation != "" {
+					got = append(got, file.Path+": "+snippet.MatchInfo+", "+snippet.Relation)
+				}
+			}
+		}
+		var want []string
+		for _, added := range tt.want {
+			want = append(want, src.Path(model)+strings.TrimPrefix(added, model))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("root %q, depth %d: Expand() added %q, want %q", root, tt.depth, got, want)
+		}
+	}
+}
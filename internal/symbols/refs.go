@@ -0,0 +1,221 @@
+package symbols
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// Reference is a block of code that uses a symbol: the function or type
+// around the usages, or the lines around them at the top level
+type Reference struct {
+	Path      string
+	Language  string
+	Rev       string // Git revision the file was read from, if not the working tree
+	Block     string // Declaration the usages are in, e.g. "Method Order.Total"; empty at the top level
+	Lines     []int  // Lines of the usages
+	StartLine int
+	EndLine   int
+	Content   string
+}
+
+// FindReferences finds the usages of symbol in files as their paths arrive,
+// in the order received. Only code counts: mentions in comments and string
+// literals are dropped, as are the symbol's own declarations.
+//
+// Without type information, a qualified symbol such as Type.Method or
+// pkg.Func is matched where the name follows ".", "->" or "::", and as a
+// bare name within its own package or type.
+func FindReferences(ctx context.Context, src finder.Source, files <-chan string, symbol Symbol, opts Options) ([]Reference, error) {
+	found, err := finder.Pipeline(ctx, src, files, opts.Limits, func(path string, _ fs.FileInfo) ([]Reference, error) {
+		data, err := src.ReadFile(path)
+		if err != nil {
+			return nil, opts.Warnings.Record(path, "read", err)
+		}
+		if !bytes.Contains(data, []byte(symbol.Name)) {
+			return nil, nil
+		}
+
+		file := indexFile(src, path, data)
+		if dataLanguages[file.language] {
+			return nil, nil
+		}
+		return file.references(symbol, opts.ContextLines), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []Reference
+	for _, fileRefs := range found {
+		refs = append(refs, fileRefs...)
+	}
+	return refs, nil
+}
+
+// references finds the usages of symbol in the file, grouped by the
+// declaration they're in
+func (f *fileIndex) references(symbol Symbol, contextLines int) []Reference {
+	code := finder.MaskExcept(f.language, f.lines, finder.KindCode)
+
+	// Usages inside a declaration are grouped under it; the rest get context lines
+	blocks := make(map[int]*Reference)
+	var order []int
+	var loose []Reference
+
+	for i, line := range code {
+		lineNum := i + 1
+		cols := nameOffsets(line, symbol.Name)
+		if len(cols) > 0 && f.declares(symbol, lineNum) {
+			// The first mention on the line is the declared name
+			cols = cols[1:]
+		}
+		for _, col := range cols {
+			if !f.qualifies(symbol, line, col, lineNum) {
+				continue
+			}
+
+			block := finder.EnclosingBlock(f.decls, lineNum)
+			if block == -1 {
+				start := max(1, lineNum-contextLines)
+				end := min(len(f.lines), lineNum+contextLines)
+				loose = append(loose, f.reference("", []int{lineNum}, start, end))
+				break
+			}
+
+			if ref, ok := blocks[block]; ok {
+				if ref.Lines[len(ref.Lines)-1] != lineNum {
+					ref.Lines = append(ref.Lines, lineNum)
+				}
+				continue
+			}
+			decl := f.decls[block]
+			ref := f.reference(describe(decl), []int{lineNum}, decl.LineNum, min(decl.EndLine, len(f.lines)))
+			blocks[block] = &ref
+			order = append(order, block)
+		}
+	}
+
+	var refs []Reference
+	for _, block := range order {
+		refs = append(refs, *blocks[block])
+	}
+	refs = append(refs, f.mergeLoose(loose)...)
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		return refs[i].StartLine < refs[j].StartLine
+	})
+	return refs
+}
+
+// declares reports whether line declares symbol itself, rather than some
+// other declaration of the same name
+func (f *fileIndex) declares(symbol Symbol, line int) bool {
+	for i, decl := range f.decls {
+		if decl.LineNum == line && f.matches(i, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameOffsets returns the offsets in line where name appears as a whole
+// identifier. "$" counts as part of identifiers, as in PHP and JavaScript,
+// so $store and store are told apart.
+func nameOffsets(line, name string) []int {
+	var offsets []int
+	for from := 0; from+len(name) <= len(line); {
+		i := strings.Index(line[from:], name)
+		if i == -1 {
+			break
+		}
+		start, end := from+i, from+i+len(name)
+		if (start == 0 || !isIdentByte(line[start-1])) && (end == len(line) || !isIdentByte(line[end])) {
+			offsets = append(offsets, start)
+		}
+		from = start + 1
+	}
+	return offsets
+}
+
+// isIdentByte reports whether b can be part of an identifier. Bytes of
+// multi-byte characters count, as letters of other scripts may be.
+func isIdentByte(b byte) bool {
+	return b == '_' || b == '$' || b >= utf8.RuneSelf ||
+		'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
+// qualifies reports whether the usage at offset col of a code line can refer
+// to symbol: always for a bare name, and for a qualified one after a member
+// access or inside the symbol's own package or type
+func (f *fileIndex) qualifies(symbol Symbol, line string, col, lineNum int) bool {
+	if symbol.Qualifier == "" && symbol.Package == "" {
+		return true
+	}
+
+	before := strings.TrimRight(line[:col], " \t")
+	if strings.HasSuffix(before, ".") || strings.HasSuffix(before, "->") || strings.HasSuffix(before, "::") {
+		// Accesses through another imported package, such as os.ReadFile
+		// for Source.ReadFile, are something else of the same name
+		if m := accessedName.FindStringSubmatch(before); m != nil && m[1] != symbol.Qualifier && m[1] != symbol.Package {
+			return !slices.Contains(f.imports, m[1])
+		}
+		return true
+	}
+
+	if symbol.Package != "" && !f.inPackage(symbol.Package) {
+		return false
+	}
+	if symbol.Qualifier == "" || f.inPackage(symbol.Qualifier) {
+		return true
+	}
+	block := finder.EnclosingBlock(f.decls, lineNum)
+	return block != -1 && (f.decls[block].Parent == symbol.Qualifier || f.decls[block].Name == symbol.Qualifier)
+}
+
+// accessedName matches the identifier before a trailing member access operator
+var accessedName = regexp.MustCompile(`([A-Za-z_$][A-Za-z0-9_$]*)\s*(?:\.|->|::)$`)
+
+// reference builds a reference covering lines start to end (1-based)
+func (f *fileIndex) reference(block string, lines []int, start, end int) Reference {
+	return Reference{
+		Path:      f.path,
+		Language:  f.language,
+		Rev:       f.rev,
+		Block:     block,
+		Lines:     lines,
+		StartLine: start,
+		EndLine:   end,
+		Content:   strings.Join(f.lines[start-1:end], "\n"),
+	}
+}
+
+// mergeLoose joins top-level usages whose context lines overlap or touch
+func (f *fileIndex) mergeLoose(loose []Reference) []Reference {
+	var merged []Reference
+	for _, ref := range loose {
+		if n := len(merged); n > 0 && ref.StartLine <= merged[n-1].EndLine+1 {
+			last := merged[n-1]
+			merged[n-1] = f.reference("", append(last.Lines, ref.Lines...), last.StartLine, max(last.EndLine, ref.EndLine))
+			continue
+		}
+		merged = append(merged, ref)
+	}
+	return merged
+}
+
+// describe names a declaration, e.g. "Method Order.Total"
+func describe(decl finder.HeaderElement) string {
+	name := decl.Name
+	if decl.Parent != "" {
+		name = decl.Parent + "." + name
+	}
+	return string(decl.Type) + " " + name
+}
@@ -0,0 +1,96 @@
+package symbols
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// refsTree has same-name symbols told apart by "$" and by their type
+var refsTree = fstest.MapFS{
+	"store.js": {Data: []byte(`init($store, store);
+
+function read() {
+  return $store.get();
+}
+
+function write(x) {
+  store.set(x);
+}
+`)},
+	"file.go": {Data: []byte(`package files
+
+type Store struct{ f *File }
+
+type File struct{}
+
+func (f *File) Close() error { return nil }
+
+func (s *Store) Close() error { return s.f.Close() }
+
+func shutdown(s *Store) { s.Close() }
+`)},
+}
+
+func TestFindReferences(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   []string
+	}{
+		{"$store", []string{"store.js:1", "store.js:3-5 Function read [4]"}},
+		{"store", []string{"store.js:1", "store.js:7-9 Function write [8]"}},
+		// The declaration of Store.Close is dropped, but the call to
+		// File.Close on the same line is a usage
+		{"Close", []string{"file.go:9-9 Method Store.Close [9]", "file.go:11-11 Function shutdown [11]"}},
+		{"File.Close", []string{"file.go:9-9 Method Store.Close [9]", "file.go:11-11 Function shutdown [11]"}},
+	}
+
+	src := finder.FSSource(refsTree, "")
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			symbol, err := ParseSymbol(tt.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			refs, err := FindReferences(ctx, src, finder.SendPaths(ctx, []string{"file.go", "store.js"}), symbol, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, ref := range refs {
+				if ref.Block == "" {
+					got = append(got, fmt.Sprintf("%s:%d", ref.Path, ref.Lines[0]))
+				} else {
+					got = append(got, fmt.Sprintf("%s:%d-%d %s %v", ref.Path, ref.StartLine, ref.EndLine, ref.Block, ref.Lines))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindReferences(%s) = %q, want %q", tt.symbol, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameOffsets(t *testing.T) {
+	tests := []struct {
+		line, name string
+		want       []int
+	}{
+		{"store.get(store)", "store", []int{0, 10}},
+		{"$store.get()", "store", nil},
+		{"$store.get()", "$store", []int{0}},
+		{"a$store + store_x + restore", "store", nil},
+		{"x=$store;$store", "$store", []int{2, 9}},
+	}
+	for _, tt := range tests {
+		if got := nameOffsets(tt.line, tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("nameOffsets(%q, %q) = %v, want %v", tt.line, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package symbols
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// identifier matches one segment of a symbol name
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// dataLanguages hold documentation or data rather than code, so mentions of a
+// name in them are neither declarations nor usages
+var dataLanguages = map[string]bool{
+	"plaintext": true, "markdown": true, "json": true, "yaml": true, "toml": true,
+	"xml": true, "html": true, "css": true, "scss": true,
+}
+
+// Symbol is a name to look up, optionally qualified by the type or class it
+// belongs to and the package or module it is declared in
+type Symbol struct {
+	Package   string // Package, module or file name; empty when not given
+	Qualifier string // Receiver type, class or package; empty when not given
+	Name      string
+}
+
+// ParseSymbol parses "Name", "Type.Method", "pkg.Func" or "pkg.Type.Method"
+func ParseSymbol(text string) (Symbol, error) {
+	parts := strings.Split(text, ".")
+	if len(parts) > 3 {
+		return Symbol{}, fmt.Errorf("invalid symbol %q: expected Name, Type.Method or pkg.Func", text)
+	}
+	for _, part := range parts {
+		if !identifier.MatchString(part) {
+			return Symbol{}, fmt.Errorf("invalid symbol %q: expected Name, Type.Method or pkg.Func", text)
+		}
+	}
+
+	symbol := Symbol{Name: parts[len(parts)-1]}
+	if len(parts) > 1 {
+		symbol.Qualifier = parts[len(parts)-2]
+	}
+	if len(parts) > 2 {
+		symbol.Package = parts[0]
+	}
+	return symbol, nil
+}
+
+// String returns the symbol as it was written
+func (s Symbol) String() string {
+	parts := []string{s.Name}
+	if s.Qualifier != "" {
+		parts = append([]string{s.Qualifier}, parts...)
+	}
+	if s.Package != "" {
+		parts = append([]string{s.Package}, parts...)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Definition is the complete text of a declaration, from its doc comment to
+// the end of its body
+type Definition struct {
+	Path      string
+	Language  string
+	Rev       string // Git revision the file was read from, if not the working tree
+	Type      finder.HeaderType
+	Name      string
+	Parent    string // Type, class or module the declaration belongs to
+	Line      int    // Line of the declaration itself
+	StartLine int    // First line of its doc comment, or Line when it has none
+	EndLine   int
+	Content   string
+}
+
+// QualifiedName returns Parent.Name, or Name for top-level declarations
+func (d Definition) QualifiedName() string {
+	if d.Parent != "" {
+		return d.Parent + "." + d.Name
+	}
+	return d.Name
+}
+
+// Options controls how files are read and references are clipped
+type Options struct {
+	ContextLines int              // Lines around usages that aren't inside any function or type
+	Limits       finder.Limits    // Concurrency and memory bounds for reading files
+	Warnings     *finder.Warnings // Collects files that couldn't be read; when nil the first error stops the lookup
+}
+
+// FindDefinitions looks up the declarations of symbol in files as their
+// paths arrive, in the order received
+func FindDefinitions(ctx context.Context, src finder.Source, files <-chan string, symbol Symbol, opts Options) ([]Definition, error) {
+	found, err := finder.Pipeline(ctx, src, files, opts.Limits, func(path string, _ fs.FileInfo) ([]Definition, error) {
+		data, err := src.ReadFile(path)
+		if err != nil {
+			return nil, opts.Warnings.Record(path, "read", err)
+		}
+
+		// Most files can't declare the symbol, so skip parsing them
+		if !bytes.Contains(data, []byte(symbol.Name)) {
+			return nil, nil
+		}
+
+		file := indexFile(src, path, data)
+		if dataLanguages[file.language] {
+			return nil, nil
+		}
+		var defs []Definition
+		for i := range file.decls {
+			if file.matches(i, symbol) {
+				defs = append(defs, file.definition(i))
+			}
+		}
+		return defs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []Definition
+	for _, fileDefs := range found {
+		defs = append(defs, fileDefs...)
+	}
+	return defs, nil
+}
+
+// fileIndex holds the declarations of one file
+type fileIndex struct {
+	path     string
+	rev      string
+	language string
+	lines    []string
+	decls    []finder.HeaderElement
+	packages []string // Names the file's declarations can be qualified with
+	imports  []string // Names the file's imports are referred to by
+}
+
+// indexFile parses the declarations of a file
+func indexFile(src finder.Source, filePath string, data []byte) *fileIndex {
+	file := &fileIndex{
+		path:     filePath,
+		rev:      src.Rev,
+		language: finder.DetectLanguageContent(filePath, data),
+		lines:    strings.Split(string(data), "\n"),
+	}
+
+	headers, _ := finder.ParseHeaders(filePath, data)
+	for _, header := range headers {
+		switch header.Type {
+		case finder.Package:
+			file.packages = append(file.packages, header.Name)
+		case finder.Import:
+			for _, imported := range header.Children {
+				file.imports = append(file.imports, importedName(imported))
+			}
+		default:
+			file.decls = append(file.decls, header)
+		}
+	}
+
+	// Declarations nested in a class or type belong to it
+	for i := range file.decls {
+		if file.decls[i].Parent == "" {
+			file.decls[i].Parent = file.container(i)
+		}
+	}
+
+	// Besides a declared package, a declaration can be qualified with the
+	// name of its directory or, as for Python modules, its file
+	base := path.Base(filePath)
+	file.packages = append(file.packages,
+		path.Base(path.Dir(filePath)),
+		strings.TrimSuffix(base, path.Ext(base)))
+
+	return file
+}
+
+// importedName returns the name an import is referred to by in the file:
+// its alias, or the last element of a Go path or first of a Python module
+func importedName(imported finder.HeaderElement) string {
+	if imported.Signature != "" {
+		return imported.Signature // Signature holds the alias
+	}
+	if strings.Contains(imported.Name, "/") {
+		return path.Base(imported.Name)
+	}
+	name, _, _ := strings.Cut(imported.Name, ".")
+	return name
+}
+
+// container returns the name of the tightest class, type or module around
+// declaration i, or "" when it is at the top level
+func (f *fileIndex) container(i int) string {
+	decl := f.decls[i]
+	best := -1
+	for j, other := range f.decls {
+		if j == i || !isContainer(other.Type) || other.EndLine < other.LineNum {
+			continue
+		}
+		if decl.LineNum <= other.LineNum || decl.LineNum > other.EndLine {
+			continue
+		}
+		if best == -1 || other.LineNum > f.decls[best].LineNum {
+			best = j
+		}
+	}
+	if best == -1 {
+		return ""
+	}
+	return f.decls[best].Name
+}
+
+// isContainer reports whether a header type can hold other declarations
+func isContainer(t finder.HeaderType) bool {
+	switch t {
+	case finder.Class, finder.Struct, finder.Interface, finder.Enum, finder.Module, finder.Namespace:
+		return true
+	}
+	return false
+}
+
+// matches reports whether declaration i is a declaration of symbol
+func (f *fileIndex) matches(i int, symbol Symbol) bool {
+	decl := f.decls[i]
+	if decl.Name != symbol.Name {
+		return false
+	}
+	if symbol.Package != "" && !f.inPackage(symbol.Package) {
+		return false
+	}
+	if symbol.Qualifier == "" {
+		return true
+	}
+	return decl.Parent == symbol.Qualifier || (symbol.Package == "" && decl.Parent == "" && f.inPackage(symbol.Qualifier))
+}
+
+// inPackage reports whether name is one of the file's package names
+func (f *fileIndex) inPackage(name string) bool {
+	return slices.Contains(f.packages, name)
+}
+
+// definition returns the complete text of declaration i with its doc comment
+func (f *fileIndex) definition(i int) Definition {
+	decl := f.decls[i]
+	end := min(max(decl.EndLine, decl.LineNum), len(f.lines))
+	start := docStart(f.language, f.lines, decl.LineNum)
+
+	return Definition{
+		Path:      f.path,
+		Language:  f.language,
+		Rev:       f.rev,
+		Type:      decl.Type,
+		Name:      decl.Name,
+		Parent:    decl.Parent,
+		Line:      decl.LineNum,
+		StartLine: start,
+		EndLine:   end,
+		Content:   strings.Join(f.lines[start-1:end], "\n"),
+	}
+}
+
+// docStart returns the first line of the comments, decorators and
+// annotations directly above the declaration at line (both 1-based)
+func docStart(language string, lines []string, line int) int {
+	// Lines that hold nothing but comments are blank once comments are masked
+	code := finder.MaskExcept(language, lines[:line-1], finder.KindCode, finder.KindString)
+
+	start := line
+	for i := line - 2; i >= 0; i-- {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			break
+		}
+		isComment := strings.TrimSpace(code[i]) == ""
+		isAnnotation := strings.HasPrefix(trimmed, "@") || strings.HasPrefix(trimmed, "#[")
+		if !isComment && !isAnnotation {
+			break
+		}
+		start = i + 1
+	}
+	return start
+}
@@ -0,0 +1,167 @@
+package symbols
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+)
+
+// defsTree declares total in several languages, with doc comments,
+// decorators and annotations above the declarations
+var defsTree = fstest.MapFS{
+	"shop/order.go": {Data: []byte(`package shop
+
+// Order is a purchase
+type Order struct {
+	Items []string
+}
+
+// Total sums the order.
+// It ignores discounts.
+func (o *Order) Total() int {
+	return len(o.Items)
+}
+
+func Total(orders []Order) int { return 0 }
+`)},
+	"app/models.py": {Data: []byte(`import functools
+
+
+class Cart:
+    """A cart."""
+
+    # Cached, as it is slow
+    @property
+    @functools.cache
+    def total(self):
+        return 0
+
+
+def total(carts):
+    return 0
+`)},
+	"web/Service.java": {Data: []byte(`package web;
+
+public class Service {
+    /**
+     * Totals things.
+     */
+    @Override
+    @Deprecated
+    public int total() {
+        return 0;
+    }
+}
+`)},
+	"lib.rs": {Data: []byte(`/// Totals things
+#[inline]
+pub fn total() -> i32 {
+    0
+}
+`)},
+	"README.md": {Data: []byte("# Total\n\nfunction total() {}\n")},
+}
+
+func TestFindDefinitions(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   []string
+	}{
+		{"Total", []string{"shop/order.go:8-12 Method Order.Total", "shop/order.go:14-14 Function Total"}},
+		{"total", []string{
+			"app/models.py:7-11 Function Cart.total",
+			"app/models.py:14-15 Function total",
+			"web/Service.java:4-11 Method Service.total",
+			"lib.rs:1-5 Function total",
+		}},
+		{"Order.Total", []string{"shop/order.go:8-12 Method Order.Total"}},
+		{"shop.Total", []string{"shop/order.go:14-14 Function Total"}},
+		{"shop.Order.Total", []string{"shop/order.go:8-12 Method Order.Total"}},
+		{"Cart.total", []string{"app/models.py:7-11 Function Cart.total"}},
+		{"models.total", []string{"app/models.py:14-15 Function total"}},
+		{"Order", []string{"shop/order.go:3-6 Struct Order"}},
+		{"Missing", nil},
+	}
+
+	src := finder.FSSource(defsTree, "")
+	paths := []string{"shop/order.go", "app/models.py", "web/Service.java", "lib.rs", "README.md"}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			symbol, err := ParseSymbol(tt.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			defs, err := FindDefinitions(ctx, src, finder.SendPaths(ctx, paths), symbol, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, def := range defs {
+				got = append(got, fmt.Sprintf("%s:%d-%d %s %s", def.Path, def.StartLine, def.EndLine, def.Type, def.QualifiedName()))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindDefinitions(%s) = %q, want %q", tt.symbol, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSymbol(t *testing.T) {
+	tests := []struct {
+		text string
+		want Symbol
+		ok   bool
+	}{
+		{"Total", Symbol{Name: "Total"}, true},
+		{"Order.Total", Symbol{Qualifier: "Order", Name: "Total"}, true},
+		{"shop.Order.Total", Symbol{Package: "shop", Qualifier: "Order", Name: "Total"}, true},
+		{"$store", Symbol{Name: "$store"}, true},
+		{"a.b.c.d", Symbol{}, false},
+		{"Order.", Symbol{}, false},
+		{"9lives", Symbol{}, false},
+		{"Order::Total", Symbol{}, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSymbol(tt.text)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseSymbol(%q) = %+v, %v, want %+v", tt.text, got, err, tt.want)
+		}
+		if tt.ok && got.String() != tt.text {
+			t.Errorf("ParseSymbol(%q).String() = %q", tt.text, got.String())
+		}
+	}
+}
+
+func TestDocStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		source   string // The declaration is on the last line
+		want     int
+	}{
+		{"none", "go", "x := 1\nfunc f() {}", 2},
+		{"line comments", "go", "x := 1\n// f does\n// things\nfunc f() {}", 2},
+		{"blank line ends the comment", "go", "// unrelated\n\nfunc f() {}", 3},
+		{"block comment", "java", "int x;\n/**\n * Does things\n */\n@Override\nvoid f() {}", 2},
+		{"decorators", "python", "x = 1\n# cached\n@property\n@functools.cache\ndef f(self):", 2},
+		{"string with a hash isn't a comment", "python", "x = \"# no\"\ndef f():", 2},
+		{"rust attribute", "rust", "/// Does things\n#[inline]\nfn f() {}", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.Split(tt.source, "\n")
+			if got := docStart(tt.language, lines, len(lines)); got != tt.want {
+				t.Errorf("docStart() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
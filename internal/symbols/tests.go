@@ -0,0 +1,175 @@
+package symbols
+
+import (
+	"context"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/grant-wade/codeclip/internal/finder"
+	"github.com/grant-wade/codeclip/internal/search"
+)
+
+// TestFunction is a function of a test file that uses some of the names
+// looked up
+type TestFunction struct {
+	Definition
+	Uses []string // Names the function uses, in the order looked up
+}
+
+// FindTests finds the functions of the test files among files that use any
+// of names in their code, as their paths arrive. Other files are skipped
+// without being read.
+func FindTests(ctx context.Context, src finder.Source, files <-chan string, names []string, opts Options) ([]TestFunction, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	pattern := regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)\b`)
+
+	found, err := finder.Pipeline(ctx, src, files, opts.Limits, func(path string, _ fs.FileInfo) ([]TestFunction, error) {
+		if !finder.IsTestFile(path) {
+			return nil, nil
+		}
+		data, err := src.ReadFile(path)
+		if err != nil {
+			return nil, opts.Warnings.Record(path, "read", err)
+		}
+		if !pattern.Match(data) {
+			return nil, nil
+		}
+
+		file := indexFile(src, path, data)
+		if dataLanguages[file.language] {
+			return nil, nil
+		}
+		return file.tests(names, pattern), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []TestFunction
+	for _, fileTests := range found {
+		tests = append(tests, fileTests...)
+	}
+	return tests, nil
+}
+
+// tests returns the functions of the file whose code uses names, each
+// attributed to the innermost function it appears in
+func (f *fileIndex) tests(names []string, pattern *regexp.Regexp) []TestFunction {
+	code := finder.MaskExcept(f.language, f.lines, finder.KindCode)
+
+	uses := make(map[int]map[string]bool)
+	for i, line := range code {
+		from := f.functionAt(i + 1)
+		if from == -1 {
+			continue
+		}
+		for _, name := range pattern.FindAllString(line, -1) {
+			if i+1 == f.decls[from].LineNum && name == f.decls[from].Name {
+				continue // A helper's own declaration
+			}
+			if uses[from] == nil {
+				uses[from] = make(map[string]bool)
+			}
+			uses[from][name] = true
+		}
+	}
+
+	var tests []TestFunction
+	for decl, used := range uses {
+		test := TestFunction{Definition: f.definition(decl)}
+		for _, name := range names {
+			if used[name] {
+				test.Uses = append(test.Uses, name)
+			}
+		}
+		tests = append(tests, test)
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		return tests[i].StartLine < tests[j].StartLine
+	})
+	return tests
+}
+
+// CoveredNames returns the names of the functions and types the results
+// cover, which their tests would use
+func CoveredNames(src finder.Source, results search.SearchResult, warnings *finder.Warnings) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, result := range results.Files {
+		data, err := src.ReadFile(result.Path)
+		if err != nil {
+			if err := warnings.Record(result.Path, "read", err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		file := indexFile(src, result.Path, data)
+		for _, decl := range file.decls {
+			if !isTestable(decl.Type) || seen[decl.Name] {
+				continue
+			}
+			for _, snippet := range result.Snippets {
+				if decl.LineNum <= snippet.EndLine && max(decl.EndLine, decl.LineNum) >= snippet.StartLine {
+					seen[decl.Name] = true
+					names = append(names, decl.Name)
+					break
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+// isTestable reports whether tests would refer to a declaration of type t
+func isTestable(t finder.HeaderType) bool {
+	switch t {
+	case finder.Function, finder.Method, finder.Class, finder.Struct, finder.Interface, finder.NamedType, finder.Enum:
+		return true
+	}
+	return false
+}
+
+// AddTests adds test functions to results as snippets of their files,
+// labelled with the names they test. Tests already in results aren't added
+// again.
+func AddTests(results search.SearchResult, tests []TestFunction) search.SearchResult {
+	for _, test := range tests {
+		if covered(results, test.Path, test.StartLine, test.EndLine) {
+			continue
+		}
+		file := &fileIndex{path: test.Path, language: test.Language, rev: test.Rev}
+		results.Files = appendSnippet(results.Files, file, search.CodeSnippet{
+			StartLine: test.StartLine,
+			EndLine:   test.EndLine,
+			Content:   test.Content,
+			MatchInfo: string(test.Type) + " " + test.QualifiedName(),
+			Relation:  "tests " + strings.Join(test.Uses, ", "),
+		})
+	}
+	return results
+}
+
+// covered reports whether a snippet of results already spans the lines
+func covered(results search.SearchResult, path string, start, end int) bool {
+	for _, file := range results.Files {
+		if file.Path != path {
+			continue
+		}
+		for _, snippet := range file.Snippets {
+			if snippet.StartLine <= start && snippet.EndLine >= end {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -2,9 +2,12 @@ package template
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"regexp"
 	"strings"
 
@@ -14,13 +17,15 @@ import (
 
 // Parser represents a template parser
 type Parser struct {
-	BasePath string
+	Source  finder.Source
+	Options finder.Options
 }
 
-// NewParser creates a new template parser
-func NewParser(basePath string) *Parser {
+// NewParser creates a new template parser that resolves tags against src
+func NewParser(src finder.Source, opts finder.Options) *Parser {
 	return &Parser{
-		BasePath: basePath,
+		Source:  src,
+		Options: opts,
 	}
 }
 
@@ -102,29 +107,32 @@ func (p *Parser) resolveTag(tag string) (string, error) {
 	return p.resolveFile(tag)
 }
 
-// resolveFile resolves a direct file reference
+// resolveFile resolves a direct file reference. Relative paths are read from
+// the source, absolute ones from disk.
 func (p *Parser) resolveFile(filePath string) (string, error) {
-	fullPath := filePath
-	if !strings.HasPrefix(filePath, "/") {
-		fullPath = fmt.Sprintf("%s/%s", p.BasePath, filePath)
+	var content []byte
+	var err error
+	if strings.HasPrefix(filePath, "/") {
+		content, err = os.ReadFile(filePath)
+	} else {
+		content, err = fs.ReadFile(p.Source.FS, path.Clean(filePath))
 	}
-
-	content, err := os.ReadFile(fullPath)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
 			// File not found, return empty string without error
 			return "", nil
 		}
-		return "", err
+		return "", p.Options.Warnings.Record(filePath, "read", err)
 	}
 
-	language := finder.DetectLanguage(filePath)
+	language := finder.DetectLanguageContent(filePath, content)
 	return fmt.Sprintf("```%s\n%s\n```", language, string(content)), nil
 }
 
-// resolveGlob resolves a glob pattern to matching files
+// resolveGlob resolves a glob pattern to matching files. The tag itself selects
+// the files; exclude and language selectors from the options still apply.
 func (p *Parser) resolveGlob(pattern string) (string, error) {
-	files, err := finder.FindFilesByGlob(p.BasePath, pattern)
+	files, err := finder.FindFilesByGlob(p.Source, pattern, p.Options)
 	if err != nil {
 		return "", err
 	}
@@ -135,10 +143,10 @@ func (p *Parser) resolveGlob(pattern string) (string, error) {
 	}
 
 	// Read all files and format them
-	fileContents, err := finder.ReadFiles(files)
+	result, err := finder.ReadFiles(p.Source, files, p.Options)
 	if err != nil {
 		return "", err
 	}
 
-	return output.FormatFiles(fileContents), nil
+	return output.FormatFiles(result.Files), nil
 }